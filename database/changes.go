@@ -0,0 +1,131 @@
+package database
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// Page change statuses, comparing a page with the previous crawl of the same start URL
+const (
+	ChangeNew       = "new"       // Not stored by the previous crawl
+	ChangeChanged   = "changed"   // Content hash differs from the previous crawl
+	ChangeUnchanged = "unchanged" // Same content hash, or the server answered 304 Not Modified
+	ChangeGone      = "gone"      // Stored by the previous crawl, now 404 or 410
+)
+
+// crawledPages excludes the placeholder rows recording pages that are gone
+func crawledPages(db *gorm.DB) *gorm.DB {
+	return db.Where("change_status <> ?", ChangeGone)
+}
+
+// withPageCount loads jobs with the number of pages they stored, counted in the database
+func withPageCount(db *gorm.DB) *gorm.DB {
+	return db.Select("jobs.*, (SELECT COUNT(*) FROM pages WHERE pages.job_id = jobs.id AND pages.change_status <> ?) AS page_count", ChangeGone)
+}
+
+// PreviousJob returns the ID of the most recent completed job before jobID with the same start URL, or 0 if there is none
+func PreviousJob(jobID uint64, url string) (uint64, error) {
+	var ids []uint64
+	err := DB.Model(&Job{}).
+		Where("url = ? AND id < ? AND status = ?", url, jobID, "completed").
+		Order("id DESC").
+		Limit(1).
+		Pluck("id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+	return ids[0], nil
+}
+
+// GetJobOptions retrieves the crawl options a job was created with
+func GetJobOptions(jobID uint64) (datatypes.JSON, error) {
+	var job Job
+	if err := DB.Select("id", "options").First(&job, jobID).Error; err != nil {
+		return nil, err
+	}
+	return job.Options, nil
+}
+
+// GetPageValidators retrieves what a job stored about each of its pages for conditional requests
+// and change detection, leaving out their content
+func GetPageValidators(jobID uint64) ([]Page, error) {
+	var pages []Page
+	if err := DB.Scopes(crawledPages).
+		Select("id, url, depth, source, etag, last_modified, content_hash, "+
+			"jsonb_strip_nulls(jsonb_build_object('request_url', metadata->'request_url')) AS metadata").
+		Where("job_id = ?", jobID).
+		Find(&pages).Error; err != nil {
+		return nil, err
+	}
+	return pages, nil
+}
+
+// AddGonePages stores placeholders for pages of a previous crawl that are no longer on the site
+func AddGonePages(pages []Page) error {
+	if len(pages) == 0 {
+		return nil
+	}
+	return DB.CreateInBatches(pages, 500).Error
+}
+
+// GetPage retrieves a stored page by ID
+func GetPage(pageID uint) (*Page, error) {
+	var page Page
+	if err := DB.First(&page, pageID).Error; err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// GetPageChanges retrieves a job's pages with the given change statuses, leaving out their content
+func GetPageChanges(jobID uint64, statuses []string) ([]Page, error) {
+	var pages []Page
+	if err := DB.Select("id", "job_id", "url", "title", "depth", "source", "content_hash", "change_status", "created_at").
+		Where("job_id = ? AND change_status IN ?", jobID, statuses).
+		Order("change_status ASC, url ASC").
+		Find(&pages).Error; err != nil {
+		return nil, err
+	}
+	return pages, nil
+}
+
+// CountPageChanges counts a job's pages by change status
+func CountPageChanges(jobID uint64) (map[string]int64, error) {
+	var rows []struct {
+		ChangeStatus string
+		Count        int64
+	}
+	if err := DB.Model(&Page{}).
+		Select("change_status, COUNT(*) AS count").
+		Where("job_id = ?", jobID).
+		Group("change_status").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.ChangeStatus] = row.Count
+	}
+	return counts, nil
+}
+
+// GetPageByURL retrieves the page a job stored for a URL
+func GetPageByURL(jobID uint64, url string) (*Page, error) {
+	var page Page
+	if err := DB.Scopes(crawledPages).Where("job_id = ? AND url = ?", jobID, url).Order("id ASC").First(&page).Error; err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// GetPageAt retrieves the latest copy of a URL stored at or before a time
+func GetPageAt(url string, at time.Time) (*Page, error) {
+	var page Page
+	if err := DB.Scopes(crawledPages).Where("url = ? AND created_at <= ?", url, at).Order("created_at DESC, id DESC").First(&page).Error; err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
@@ -1,6 +1,7 @@
 package database
 
 import (
+	"context"
 	"encoding/json"
 	"log"
 	"os"
@@ -10,20 +11,31 @@ import (
 	"gorm.io/datatypes"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"gorm.io/gorm/logger"
 )
 
 var DB *gorm.DB
 
+// ErrNotFound is returned when a record doesn't exist
+var ErrNotFound = gorm.ErrRecordNotFound
+
 // Job represents a scheduled crawling task
 type Job struct {
-	ID          uint64     `gorm:"primaryKey"`
-	Status      string     `gorm:"type:varchar(20);default:'queued'"` // queued, in_progress, completed, failed
-	Priority    int        `gorm:"default:1"`                         // 1 = low, 2 = medium, 3 = high
-	CreatedAt   time.Time  `gorm:"autoCreateTime"`
-	StartedAt   *time.Time // Nullable, records when the job starts
-	CompletedAt *time.Time // Nullable, records when the job finishes
-	Pages       []Page     `gorm:"foreignKey:JobID"` // One-to-Many Relationship
+	ID             uint64         `gorm:"primaryKey"`
+	URL            string         // Start URL
+	Options        datatypes.JSON `gorm:"type:jsonb"`                        // Crawl options, kept so the job can be resumed
+	Status         string         `gorm:"type:varchar(20);default:'queued'"` // queued, in_progress, paused, completed, failed, canceled
+	Priority       int            `gorm:"default:1"`                         // 1 = low, 2 = medium, 3 = high
+	CreatedAt      time.Time      `gorm:"autoCreateTime"`
+	StartedAt      *time.Time     // Nullable, records when the job starts
+	CompletedAt    *time.Time     // Nullable, records when the job finishes
+	LeaseOwner     string         `gorm:"type:varchar(255);default:'';index"` // Node running the job, or the node a queued job is pinned to
+	LeaseExpiresAt *time.Time     // Nullable, the job is reassigned if its owner doesn't renew the lease by then
+	Summary        datatypes.JSON `gorm:"type:jsonb"`       // JobSummary, written when the job completes or is canceled
+	ScheduleID     *uint64        `gorm:"index"`            // Nullable, the schedule that queued the job
+	Pages          []Page         `gorm:"foreignKey:JobID"` // One-to-Many Relationship
+	PageCount      int            `gorm:"->;-:migration"`   // Stored pages, loaded by withPageCount instead of the pages themselves
 }
 
 // Page represents a crawled webpage
@@ -33,8 +45,35 @@ type Page struct {
 	URL       string
 	Title     string
 	Content   string         `gorm:"type:text"`
-	Metadata  datatypes.JSON `gorm:"type:jsonb"` // Store structured metadata
+	Depth     int            `gorm:"index"`            // Hop distance from the job's start URL
+	Source    string         `gorm:"type:varchar(20)"` // seed, link, sitemap or previous
+	Metadata  datatypes.JSON `gorm:"type:jsonb"`       // Store structured metadata
 	CreatedAt time.Time      `gorm:"autoCreateTime"`
+
+	ETag         string `gorm:"column:etag"`                       // ETag response header, sent as If-None-Match when the URL is crawled again
+	LastModified string `gorm:"type:varchar(64)"`                  // Last-Modified response header, sent as If-Modified-Since
+	ContentHash  string `gorm:"type:varchar(64)"`                  // SHA-256 of the page's title and text
+	ChangeStatus string `gorm:"type:varchar(20);default:'';index"` // new, changed, unchanged or gone compared with the previous crawl of the start URL
+
+	ContentHTML string `gorm:"type:text"` // Main content with boilerplate and presentational attributes stripped, empty in raw content mode
+	Markdown    string `gorm:"type:text"` // Main content, or the whole body in raw content mode, as Markdown
+
+	Extracted datatypes.JSON `gorm:"type:jsonb"` // Record extracted with the job's extraction schema, null if it has none or it doesn't apply
+
+	PageMetrics
+}
+
+// FetchAttempt records a failed or skipped fetch of a URL
+type FetchAttempt struct {
+	ID         uint   `gorm:"primaryKey"`
+	JobID      uint64 `gorm:"index"` // Foreign key to jobs
+	URL        string
+	Attempt    int    // 1-based request number, 0 if the URL was skipped without a request
+	ErrorClass string `gorm:"type:varchar(20)"` // dns, tls, timeout, network, http_status, redirect, parse, too_large, content_type, robots_blocked
+	StatusCode int
+	Error      string
+	Final      bool      `gorm:"index"` // The URL was given up on after this attempt
+	CreatedAt  time.Time `gorm:"autoCreateTime"`
 }
 
 // InitDatabase initializes the PostgreSQL database connection from environment variables
@@ -54,15 +93,25 @@ func InitDatabase() {
 	}
 
 	// Auto Migrate the schema
-	err = DB.AutoMigrate(&Job{}, &Page{})
+	err = DB.AutoMigrate(&Job{}, &Page{}, &FetchAttempt{}, &FrontierEntry{}, &Schedule{}, &Leader{}, &Link{}, &Redirect{}, &LinkCheck{})
 	if err != nil {
 		log.Fatalf("Failed to migrate database schema: %v", err)
 	}
 }
 
-// CreateJob adds a new job entry
-func CreateJob(priority int) (*Job, error) {
-	job := &Job{Priority: priority, Status: "queued"}
+// CreateJob adds a new job entry, encoding its crawl options as JSON.
+// A non-empty owner pins the queued job to that node for as long as the node renews its lease.
+func CreateJob(url string, priority int, options interface{}, owner string) (*Job, error) {
+	optionsJSON, err := json.Marshal(options)
+	if err != nil {
+		return nil, err
+	}
+
+	job := &Job{URL: url, Options: datatypes.JSON(optionsJSON), Priority: priority, Status: "queued"}
+	if owner != "" {
+		expiresAt := time.Now().Add(leaseDuration)
+		job.LeaseOwner, job.LeaseExpiresAt = owner, &expiresAt
+	}
 	if err := DB.Create(job).Error; err != nil {
 		return nil, err
 	}
@@ -72,7 +121,16 @@ func CreateJob(priority int) (*Job, error) {
 // GetJob retrieves a job by ID
 func GetJob(jobID uint64) (*Job, error) {
 	var job Job
-	if err := DB.Preload("Pages").First(&job, jobID).Error; err != nil {
+	if err := DB.Scopes(withPageCount).First(&job, jobID).Error; err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// LookupJob retrieves only a job's ID and start URL, to check that it exists without loading its pages
+func LookupJob(jobID uint64) (*Job, error) {
+	var job Job
+	if err := DB.Select("id", "url").First(&job, jobID).Error; err != nil {
 		return nil, err
 	}
 	return &job, nil
@@ -81,31 +139,135 @@ func GetJob(jobID uint64) (*Job, error) {
 // GetAllJobs retrieves all jobs
 func GetAllJobs() ([]Job, error) {
 	var jobs []Job
-	if err := DB.Order("id DESC").Preload("Pages").Find(&jobs).Error; err != nil {
+	if err := DB.Order("id DESC").Scopes(withPageCount).Find(&jobs).Error; err != nil {
 		return nil, err
 	}
 	return jobs, nil
 }
 
+// GetPages retrieves the pages of a job, optionally restricted to a single depth (depth < 0 = all)
+func GetPages(jobID uint64, depth int) ([]Page, error) {
+	var pages []Page
+	query := DB.Scopes(crawledPages).Where("job_id = ?", jobID)
+	if depth >= 0 {
+		query = query.Where("depth = ?", depth)
+	}
+	if err := query.Order("depth ASC, id ASC").Find(&pages).Error; err != nil {
+		return nil, err
+	}
+	return pages, nil
+}
+
+// GetPageHeaders retrieves the URL, title and depth of a job's stored pages in the order of GetPages,
+// without their content
+func GetPageHeaders(jobID uint64) ([]Page, error) {
+	var pages []Page
+	if err := DB.Scopes(crawledPages).Select("url", "title", "depth").Where("job_id = ?", jobID).
+		Order("depth ASC, id ASC").Find(&pages).Error; err != nil {
+		return nil, err
+	}
+	return pages, nil
+}
+
 // UpdateJobStatus updates the job's status
 func UpdateJobStatus(jobID uint64, status string) error {
 	return DB.Model(&Job{}).Where("id = ?", jobID).Update("status", status).Error
 }
 
-func AddPage(jobID uint64, url, title, content string, metadata map[string]interface{}) error {
+// JobSummary records what a job got done by the time it completed or was canceled
+type JobSummary struct {
+	Pages     int64 `json:"pages"`     // Pages stored
+	Failed    int64 `json:"failed"`    // URLs given up on after fetch errors
+	Skipped   int64 `json:"skipped"`   // URLs deliberately left uncrawled: robots.txt, size, type or duplicates
+	Remaining int64 `json:"remaining"` // URLs still queued or in flight when the job stopped
+}
+
+// FinishJob sets a job's terminal status, completion time and summary
+func FinishJob(jobID uint64, status string) (*JobSummary, error) {
+	summary := &JobSummary{}
+	counts := []struct {
+		target *int64
+		query  *gorm.DB
+	}{
+		{&summary.Pages, DB.Model(&Page{}).Scopes(crawledPages).Where("job_id = ?", jobID)},
+		{&summary.Failed, DB.Model(&FetchAttempt{}).Where("job_id = ? AND final", jobID).
+			Where("NOT EXISTS (?)", DB.Model(&FrontierEntry{}).Select("1").
+				Where("frontier_entries.job_id = fetch_attempts.job_id AND frontier_entries.url = fetch_attempts.url AND frontier_entries.state = ?", FrontierSkipped))},
+		{&summary.Skipped, DB.Model(&FrontierEntry{}).Where("job_id = ? AND state = ?", jobID, FrontierSkipped)},
+		{&summary.Remaining, DB.Model(&FrontierEntry{}).Where("job_id = ? AND state = ?", jobID, FrontierQueued)},
+	}
+	for _, count := range counts {
+		if err := count.query.Count(count.target).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	summaryJSON, err := json.Marshal(summary)
+	if err != nil {
+		return nil, err
+	}
+
+	return summary, DB.Model(&Job{}).Where("id = ?", jobID).Updates(map[string]interface{}{
+		"status":       status,
+		"completed_at": time.Now(),
+		"summary":      datatypes.JSON(summaryJSON),
+	}).Error
+}
+
+// TransitionJob applies updates to a job if its status is one of from, and returns the status it had.
+// It returns ErrNotFound if the job doesn't exist.
+func TransitionJob(jobID uint64, from []string, updates map[string]interface{}) (string, error) {
+	var previous string
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		var job Job
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "status").First(&job, jobID).Error; err != nil {
+			return err
+		}
+		previous = job.Status
+
+		for _, status := range from {
+			if job.Status == status {
+				return tx.Model(&job).Updates(updates).Error
+			}
+		}
+		return nil
+	})
+	return previous, err
+}
+
+// AddPage stores a crawled page for a job, encoding its metadata as JSON
+func AddPage(ctx context.Context, page *Page, metadata map[string]interface{}) error {
 	metadataJSON, err := json.Marshal(metadata) // Convert map to JSON
 	if err != nil {
 		return err
 	}
 
-	page := &Page{
-		JobID:    jobID,
-		URL:      url,
-		Title:    title,
-		Content:  content,
-		Metadata: datatypes.JSON(metadataJSON), // Store JSON in PostgreSQL
+	page.Metadata = datatypes.JSON(metadataJSON) // Store JSON in PostgreSQL
+	return DB.WithContext(ctx).Create(page).Error
+}
+
+// AddFetchAttempt records a failed or skipped fetch
+func AddFetchAttempt(ctx context.Context, attempt *FetchAttempt) error {
+	return DB.WithContext(ctx).Create(attempt).Error
+}
+
+// GetFailures retrieves the final failed attempt of every URL a job gave up on
+func GetFailures(jobID uint64) ([]FetchAttempt, error) {
+	var failures []FetchAttempt
+	if err := DB.Where("job_id = ? AND final", jobID).Order("id ASC").Find(&failures).Error; err != nil {
+		return nil, err
+	}
+	return failures, nil
+}
+
+// GetSkippedAttempts retrieves the URLs of a job that were deliberately not crawled, by the classes of
+// their final attempt
+func GetSkippedAttempts(jobID uint64, classes []string) ([]FetchAttempt, error) {
+	var skipped []FetchAttempt
+	if err := DB.Where("job_id = ? AND final AND error_class IN ?", jobID, classes).Order("id ASC").Find(&skipped).Error; err != nil {
+		return nil, err
 	}
-	return DB.Create(page).Error
+	return skipped, nil
 }
 
 // DeleteJob removes a job and its associated pages from the database.
@@ -119,6 +281,31 @@ func DeleteJob(jobID uint64) error {
 		return err
 	}
 
+	if err := tx.Where("job_id = ?", jobID).Delete(&FetchAttempt{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Where("job_id = ?", jobID).Delete(&FrontierEntry{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Where("job_id = ?", jobID).Delete(&Link{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Where("job_id = ?", jobID).Delete(&Redirect{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Where("job_id = ?", jobID).Delete(&LinkCheck{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	// Delete the job itself
 	if err := tx.Where("id = ?", jobID).Delete(&Job{}).Error; err != nil {
 		tx.Rollback()
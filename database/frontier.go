@@ -0,0 +1,82 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// Frontier entry states
+const (
+	FrontierQueued  = "queued"  // Waiting to be crawled, or in flight when the process stopped
+	FrontierDone    = "done"    // Crawled or given up on, counted against max_links
+	FrontierSkipped = "skipped" // Deliberately not crawled, not counted against max_links
+	FrontierClaimed = "claimed" // Redirect target or canonical URL of a crawled page, never queued
+)
+
+// FrontierEntry is a URL a job has discovered, persisted so an interrupted crawl can resume
+type FrontierEntry struct {
+	ID        uint64 `gorm:"primaryKey"`
+	JobID     uint64 `gorm:"uniqueIndex:idx_frontier_job_url"`
+	URL       string `gorm:"uniqueIndex:idx_frontier_job_url"` // Normalized URL
+	FetchURL  string // URL as written, if it differs from the normalized one
+	Depth     int
+	Priority  float64
+	LastMod   *time.Time
+	Source    string    `gorm:"type:varchar(20)"` // seed, link, sitemap or previous
+	State     string    `gorm:"type:varchar(20)"` // queued, done, skipped, claimed
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
+
+// AddFrontierEntries stores newly discovered URLs, ignoring ones the job already knows
+func AddFrontierEntries(ctx context.Context, entries []FrontierEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	return DB.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(entries, 500).Error
+}
+
+// SettleFrontierEntry moves a queued URL to done or skipped; URLs already settled are left alone
+func SettleFrontierEntry(ctx context.Context, jobID uint64, url, state string) error {
+	return DB.WithContext(ctx).Model(&FrontierEntry{}).
+		Where("job_id = ? AND url = ? AND state = ?", jobID, url, FrontierQueued).
+		Update("state", state).Error
+}
+
+// GetFrontier retrieves every URL a job has discovered, in discovery order
+func GetFrontier(ctx context.Context, jobID uint64) ([]FrontierEntry, error) {
+	var entries []FrontierEntry
+	if err := DB.WithContext(ctx).Where("job_id = ?", jobID).Order("id ASC").Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// GetStoredURLs returns the URLs a job has stored pages under, and the URLs its canonicalized pages were fetched from
+func GetStoredURLs(ctx context.Context, jobID uint64) ([]string, error) {
+	var rows []struct {
+		URL        string
+		FetchedURL string
+	}
+	err := DB.WithContext(ctx).Model(&Page{}).
+		Select("url, COALESCE(metadata->>'fetched_url', '') AS fetched_url").
+		Where("job_id = ?", jobID).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	urls := make([]string, 0, len(rows))
+	for _, row := range rows {
+		urls = append(urls, row.URL)
+		if row.FetchedURL != "" {
+			urls = append(urls, row.FetchedURL)
+		}
+	}
+	return urls, nil
+}
+
+// ClearFrontier removes a finished job's frontier
+func ClearFrontier(jobID uint64) error {
+	return DB.Where("job_id = ?", jobID).Delete(&FrontierEntry{}).Error
+}
@@ -0,0 +1,87 @@
+package database
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// leaseDuration is how long a node may go without renewing its leases before its jobs are reassigned
+const leaseDuration = 30 * time.Second
+
+// activeStatuses are the job states a lease applies to
+var activeStatuses = []string{"queued", "in_progress"}
+
+// ClaimJob leases the next runnable job to a node: queued jobs by priority then age, and running
+// jobs whose owner stopped renewing its lease. It returns a nil job when nothing is runnable.
+func ClaimJob(owner string) (job *Job, err error) {
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		var candidates []Job
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("(status = ? AND (COALESCE(lease_owner, '') IN ('', ?) OR lease_expires_at < ?))"+
+				" OR (status = ? AND (lease_expires_at IS NULL OR lease_expires_at < ?))",
+				"queued", owner, now, "in_progress", now).
+			Order("priority DESC, created_at ASC, id ASC").
+			Limit(1).
+			Find(&candidates).Error; err != nil {
+			return err
+		}
+		if len(candidates) == 0 {
+			return nil
+		}
+
+		job = &candidates[0]
+
+		updates := map[string]interface{}{
+			"status":           "in_progress",
+			"lease_owner":      owner,
+			"lease_expires_at": now.Add(leaseDuration),
+		}
+		if job.StartedAt == nil {
+			updates["started_at"] = now
+		}
+		return tx.Model(job).Updates(updates).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// RenewLeases extends every lease a node holds and returns the IDs of the jobs it still owns
+func RenewLeases(owner string) ([]uint64, error) {
+	if err := DB.Model(&Job{}).
+		Where("lease_owner = ? AND status IN ?", owner, activeStatuses).
+		Update("lease_expires_at", time.Now().Add(leaseDuration)).Error; err != nil {
+		return nil, err
+	}
+
+	var owned []uint64
+	if err := DB.Model(&Job{}).
+		Where("lease_owner = ? AND status IN ?", owner, activeStatuses).
+		Pluck("id", &owned).Error; err != nil {
+		return nil, err
+	}
+	return owned, nil
+}
+
+// ReleaseLeases hands back the unfinished jobs a node owned, so they can be claimed again right away
+func ReleaseLeases(owner string) error {
+	return DB.Model(&Job{}).
+		Where("lease_owner = ? AND status IN ?", owner, activeStatuses).
+		Updates(map[string]interface{}{"lease_owner": "", "lease_expires_at": nil}).Error
+}
+
+// QueuePosition returns a queued job's 1-based place in line
+func QueuePosition(job *Job) (int, error) {
+	var ahead int64
+	err := DB.Model(&Job{}).
+		Where("status = ?", "queued").
+		Where("priority > ? OR (priority = ? AND (created_at < ? OR (created_at = ? AND id < ?)))",
+			job.Priority, job.Priority, job.CreatedAt, job.CreatedAt, job.ID).
+		Count(&ahead).Error
+	return int(ahead) + 1, err
+}
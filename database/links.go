@@ -0,0 +1,139 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm/clause"
+)
+
+// Link is an <a href> found on a crawled page
+type Link struct {
+	ID        uint64 `gorm:"primaryKey"`
+	JobID     uint64 `gorm:"index:idx_links_job_target,priority:1"` // Foreign key to jobs
+	PageID    uint   `gorm:"index"`                                 // Page the link is on
+	SourceURL string // URL the page is stored under
+	TargetURL string `gorm:"index:idx_links_job_target,priority:2"` // Absolute and normalized like the frontier
+	Href      string // Absolute target as written, if it differs from TargetURL
+	Anchor    string // Link text, or the alt text of a linked image
+	Nofollow  bool
+	Sponsored bool
+	UGC       bool `gorm:"column:ugc"`
+	Internal  bool // Target is on the crawled site: the start host, or its domain in domain scope
+	Crawled   bool // Target was fetched by the job, set when the job finishes
+}
+
+// Redirect is the redirect chain a fetch of a URL followed, kept even when the page wasn't stored
+type Redirect struct {
+	ID          uint64         `gorm:"primaryKey"`
+	JobID       uint64         `gorm:"uniqueIndex:idx_redirects_job_url"` // Foreign key to jobs
+	URL         string         `gorm:"uniqueIndex:idx_redirects_job_url"` // URL that was requested
+	Hops        datatypes.JSON `gorm:"type:jsonb"`                        // Redirect responses in order, [{url, status}]
+	FinalURL    string         // Where the chain ended, or the next hop it was stopped at
+	FinalStatus int            // Status of the final response, 0 if the chain was stopped
+	Loop        bool           // The chain led back to a URL it had already visited
+	CreatedAt   time.Time      `gorm:"autoCreateTime"`
+}
+
+// LinkCheck is the result of checking an external link target without crawling it
+type LinkCheck struct {
+	ID         uint64 `gorm:"primaryKey"`
+	JobID      uint64 `gorm:"index"` // Foreign key to jobs
+	URL        string
+	StatusCode int
+	ErrorClass string `gorm:"type:varchar(20)"` // Set if the target is broken: http_status, dns, tls, timeout, network, redirect
+	Error      string
+	CheckedAt  time.Time `gorm:"autoCreateTime"`
+}
+
+// AddLinks stores the links found on a page
+func AddLinks(ctx context.Context, links []Link) error {
+	if len(links) == 0 {
+		return nil
+	}
+	return DB.WithContext(ctx).CreateInBatches(links, 500).Error
+}
+
+// CopyLinks stores the links of a previously crawled page again for a page copied from it
+func CopyLinks(ctx context.Context, previousPageID uint, page *Page) error {
+	var links []Link
+	if err := DB.WithContext(ctx).Where("page_id = ?", previousPageID).Order("id ASC").Find(&links).Error; err != nil {
+		return err
+	}
+	for i := range links {
+		links[i].ID = 0
+		links[i].JobID = page.JobID
+		links[i].PageID = page.ID
+		links[i].SourceURL = page.URL
+		links[i].Crawled = false
+	}
+	return AddLinks(ctx, links)
+}
+
+// MarkCrawledLinks flags the links whose target the job fetched, or stored a page under
+func MarkCrawledLinks(jobID uint64) error {
+	fetched := DB.Model(&FrontierEntry{}).Select("url").Where("job_id = ? AND state IN ?", jobID, []string{FrontierDone, FrontierClaimed})
+	stored := DB.Model(&Page{}).Scopes(crawledPages).Select("url").Where("job_id = ?", jobID)
+	return DB.Model(&Link{}).
+		Where("job_id = ? AND NOT crawled", jobID).
+		Where("target_url IN (?) OR target_url IN (?)", fetched, stored).
+		Update("crawled", true).Error
+}
+
+// GetLinks retrieves every link a job found, in discovery order
+func GetLinks(jobID uint64) ([]Link, error) {
+	var links []Link
+	if err := DB.Where("job_id = ?", jobID).Order("id ASC").Find(&links).Error; err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+// ExternalTarget is an external link target with a URL to request it by
+type ExternalTarget struct {
+	TargetURL string // Normalized URL the check is recorded under
+	Href      string // One of the ways pages wrote it, empty if always as normalized
+}
+
+// GetExternalTargets returns up to limit distinct external link targets of a job that haven't been checked, most linked first
+func GetExternalTargets(jobID uint64, limit int) ([]ExternalTarget, error) {
+	var targets []ExternalTarget
+	err := DB.Model(&Link{}).Select("target_url, MIN(href) AS href").
+		Where("job_id = ? AND NOT internal", jobID).
+		Where("target_url NOT IN (?)", DB.Model(&LinkCheck{}).Select("url").Where("job_id = ?", jobID)).
+		Group("target_url").Order("COUNT(*) DESC, target_url ASC").Limit(limit).
+		Scan(&targets).Error
+	return targets, err
+}
+
+// SaveRedirect stores the redirect chain of a URL, replacing the one of an earlier attempt
+func SaveRedirect(ctx context.Context, redirect *Redirect) error {
+	return DB.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "job_id"}, {Name: "url"}},
+		DoUpdates: clause.AssignmentColumns([]string{"hops", "final_url", "final_status", "loop"}),
+	}).Create(redirect).Error
+}
+
+// GetRedirects retrieves the redirect chains a job followed, in the order they were first seen
+func GetRedirects(jobID uint64) ([]Redirect, error) {
+	var redirects []Redirect
+	if err := DB.Where("job_id = ?", jobID).Order("id ASC").Find(&redirects).Error; err != nil {
+		return nil, err
+	}
+	return redirects, nil
+}
+
+// AddLinkCheck records the result of checking an external link
+func AddLinkCheck(ctx context.Context, check *LinkCheck) error {
+	return DB.WithContext(ctx).Create(check).Error
+}
+
+// GetLinkChecks retrieves the external link checks of a job
+func GetLinkChecks(jobID uint64) ([]LinkCheck, error) {
+	var checks []LinkCheck
+	if err := DB.Where("job_id = ?", jobID).Order("id ASC").Find(&checks).Error; err != nil {
+		return nil, err
+	}
+	return checks, nil
+}
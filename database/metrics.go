@@ -0,0 +1,101 @@
+package database
+
+import (
+	"gorm.io/gorm"
+)
+
+// PageMetrics are the internal link metrics of a page, computed from the job's link graph when the job finishes
+type PageMetrics struct {
+	PageRank   float64 `gorm:"column:pagerank"` // Share of the site's internal PageRank, summing to 1 over the job's pages
+	InDegree   int     // Other stored pages linking to the page
+	OutDegree  int     // Distinct internal URLs the page links to, itself excluded
+	ClickDepth *int    // Fewest clicks from the start URL along internal links, nil if it can't be reached
+	Orphan     bool    // Listed in a sitemap but not linked from any stored page
+	NoOutlinks bool    // Links to no other internal URL
+}
+
+// pageMetricsColumns are written by UpdatePageMetrics, including zero values
+var pageMetricsColumns = []string{"pagerank", "in_degree", "out_degree", "click_depth", "orphan", "no_outlinks"}
+
+// PageMetricsSorts maps the sort keys of GetPageMetrics to their columns
+var PageMetricsSorts = map[string]string{
+	"pagerank":    "pagerank",
+	"in_degree":   "in_degree",
+	"out_degree":  "out_degree",
+	"click_depth": "click_depth",
+	"depth":       "depth",
+	"url":         "url",
+}
+
+// PageNode is a stored page as a node of the link graph
+type PageNode struct {
+	ID         uint
+	URL        string
+	Source     string
+	FetchedURL string // URL the page was fetched from if it is stored under its canonical URL
+}
+
+// PageMetricsQuery filters, orders and pages the results of GetPageMetrics
+type PageMetricsQuery struct {
+	Sort       string // Key of PageMetricsSorts
+	Descending bool
+	Orphan     bool // Only orphan pages
+	NoOutlinks bool // Only pages without internal outlinks
+	Limit      int
+	Offset     int
+}
+
+// GetPageNodes retrieves the stored pages of a job without their content
+func GetPageNodes(jobID uint64) ([]PageNode, error) {
+	var nodes []PageNode
+	err := DB.Model(&Page{}).Scopes(crawledPages).
+		Select("id, url, source, metadata->>'fetched_url' AS fetched_url").
+		Where("job_id = ?", jobID).Order("id ASC").Scan(&nodes).Error
+	return nodes, err
+}
+
+// UpdatePageMetrics stores the link metrics of a job's pages by page ID
+func UpdatePageMetrics(jobID uint64, metrics map[uint]PageMetrics) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		for pageID, pageMetrics := range metrics {
+			err := tx.Model(&Page{}).Where("id = ? AND job_id = ?", pageID, jobID).
+				Select(pageMetricsColumns).Updates(&Page{PageMetrics: pageMetrics}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetPageMetrics retrieves the stored pages of a job without their content, sorted by a metric,
+// along with how many pages match the filters
+func GetPageMetrics(jobID uint64, query PageMetricsQuery) ([]Page, int64, error) {
+	filtered := DB.Model(&Page{}).Scopes(crawledPages).Where("job_id = ?", jobID)
+	if query.Orphan {
+		filtered = filtered.Where("orphan")
+	}
+	if query.NoOutlinks {
+		filtered = filtered.Where("no_outlinks")
+	}
+
+	var total int64
+	if err := filtered.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	column, ok := PageMetricsSorts[query.Sort]
+	if !ok {
+		column = PageMetricsSorts["pagerank"]
+	}
+	direction := "ASC"
+	if query.Descending {
+		direction = "DESC"
+	}
+
+	var pages []Page
+	err := filtered.Select(append([]string{"id", "job_id", "url", "title", "depth", "source"}, pageMetricsColumns...)).
+		Order(column + " " + direction + " NULLS LAST").Order("id ASC").
+		Limit(query.Limit).Offset(query.Offset).Find(&pages).Error
+	return pages, total, err
+}
@@ -0,0 +1,151 @@
+package database
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Schedule is a crawl that is queued again every time its cron expression fires
+type Schedule struct {
+	ID        uint64         `gorm:"primaryKey" json:"id"`
+	Name      string         `json:"name"`
+	Cron      string         `gorm:"type:varchar(100)" json:"cron"`                  // Five-field cron expression or @daily style macro
+	Timezone  string         `gorm:"type:varchar(64);default:'UTC'" json:"timezone"` // IANA zone the expression is evaluated in
+	URL       string         `json:"url"`                                            // Seed URL of every run
+	Options   datatypes.JSON `gorm:"type:jsonb" json:"options"`                      // Crawl options of every run
+	Enabled   bool           `gorm:"default:true" json:"enabled"`
+	NextRunAt *time.Time     `gorm:"index" json:"next_run_at"` // Nullable, unset while the schedule is disabled
+	LastRunAt *time.Time     `json:"last_run_at"`
+	LastJobID *uint64        `json:"last_job_id"`
+	CreatedAt time.Time      `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// Leader is a named lease held by one node at a time, for work that must not run on every replica
+type Leader struct {
+	Name      string `gorm:"primaryKey;type:varchar(64)"`
+	Owner     string `gorm:"type:varchar(255)"`
+	ExpiresAt time.Time
+}
+
+// CreateSchedule stores a new schedule
+func CreateSchedule(schedule *Schedule) error {
+	return DB.Create(schedule).Error
+}
+
+// GetSchedule retrieves a schedule by ID
+func GetSchedule(scheduleID uint64) (*Schedule, error) {
+	var schedule Schedule
+	if err := DB.First(&schedule, scheduleID).Error; err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+// GetSchedules retrieves all schedules
+func GetSchedules() ([]Schedule, error) {
+	var schedules []Schedule
+	if err := DB.Order("id ASC").Find(&schedules).Error; err != nil {
+		return nil, err
+	}
+	return schedules, nil
+}
+
+// UpdateSchedule replaces a schedule's definition and next run time.
+// It returns ErrNotFound if the schedule doesn't exist.
+func UpdateSchedule(schedule *Schedule) error {
+	result := DB.Model(&Schedule{}).Where("id = ?", schedule.ID).Updates(map[string]interface{}{
+		"name":        schedule.Name,
+		"cron":        schedule.Cron,
+		"timezone":    schedule.Timezone,
+		"url":         schedule.URL,
+		"options":     schedule.Options,
+		"enabled":     schedule.Enabled,
+		"next_run_at": schedule.NextRunAt,
+		"updated_at":  time.Now(),
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteSchedule removes a schedule; the jobs it spawned are kept.
+// It returns ErrNotFound if the schedule doesn't exist.
+func DeleteSchedule(scheduleID uint64) error {
+	result := DB.Delete(&Schedule{}, scheduleID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// GetDueSchedules retrieves the enabled schedules whose next run time has passed
+func GetDueSchedules(now time.Time) ([]Schedule, error) {
+	var schedules []Schedule
+	if err := DB.Where("enabled AND next_run_at <= ?", now).Order("next_run_at ASC").Find(&schedules).Error; err != nil {
+		return nil, err
+	}
+	return schedules, nil
+}
+
+// FireSchedule queues a run of a due schedule and moves it on to its next run time.
+// It returns a nil job if the run was already queued, by another node or after the schedule changed.
+func FireSchedule(schedule *Schedule, next time.Time, priority int) (job *Job, err error) {
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		// Only the node that moves next_run_at on queues the run
+		result := tx.Model(&Schedule{}).
+			Where("id = ? AND enabled AND next_run_at = ?", schedule.ID, schedule.NextRunAt).
+			Updates(map[string]interface{}{"next_run_at": next, "last_run_at": now})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+
+		job = &Job{URL: schedule.URL, Options: schedule.Options, Priority: priority, Status: "queued", ScheduleID: &schedule.ID}
+		if err := tx.Create(job).Error; err != nil {
+			return err
+		}
+		return tx.Model(&Schedule{}).Where("id = ?", schedule.ID).Update("last_job_id", job.ID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// GetScheduleJobs retrieves the jobs a schedule spawned, newest first
+func GetScheduleJobs(scheduleID uint64) ([]Job, error) {
+	var jobs []Job
+	if err := DB.Where("schedule_id = ?", scheduleID).Order("id DESC").Scopes(withPageCount).Find(&jobs).Error; err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// AcquireLeadership takes or renews the named lease for a node, reporting whether the node holds it.
+// A lease passes to another node only after its owner stops renewing it for ttl.
+func AcquireLeadership(name, owner string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	result := DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"owner", "expires_at"}),
+		Where: clause.Where{Exprs: []clause.Expression{
+			clause.Expr{SQL: "leaders.owner = ? OR leaders.expires_at < ?", Vars: []interface{}{owner, now}},
+		}},
+	}).Create(&Leader{Name: name, Owner: owner, ExpiresAt: now.Add(ttl)})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
@@ -0,0 +1,342 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Op is the kind of an edit
+type Op string
+
+const (
+	Equal  Op = "equal"
+	Insert Op = "insert"
+	Delete Op = "delete"
+)
+
+// maxEditDistance bounds the work spent on a diff; inputs that differ more are reported as fully replaced
+const maxEditDistance = 1000
+
+// Edit is a run of text that is kept, inserted or deleted
+type Edit struct {
+	Op   Op     `json:"op"`
+	Text string `json:"text"`
+}
+
+// Lines diffs two texts line by line
+func Lines(a, b string) []Edit {
+	return merge(tokens(splitLines(a), splitLines(b)))
+}
+
+// Words diffs two texts word by word, keeping whitespace as its own tokens
+func Words(a, b string) []Edit {
+	return merge(tokens(splitWords(a), splitWords(b)))
+}
+
+// Count returns how many runes the edits insert and delete
+func Count(edits []Edit) (inserted, deleted int) {
+	for _, edit := range edits {
+		switch edit.Op {
+		case Insert:
+			inserted += len([]rune(edit.Text))
+		case Delete:
+			deleted += len([]rune(edit.Text))
+		}
+	}
+	return inserted, deleted
+}
+
+// Changed reports whether the edits change anything
+func Changed(edits []Edit) bool {
+	for _, edit := range edits {
+		if edit.Op != Equal {
+			return true
+		}
+	}
+	return false
+}
+
+// Unified renders a line diff of two texts in unified diff format with the given lines of context.
+// It returns an empty string if the texts are equal.
+func Unified(fromName, toName, a, b string, context int) string {
+	edits := tokens(splitLines(a), splitLines(b))
+	if !Changed(edits) {
+		return ""
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromName, toName)
+
+	// Line numbers before each edit, for hunk headers
+	aLine, bLine := make([]int, len(edits)+1), make([]int, len(edits)+1)
+	for i, edit := range edits {
+		aLine[i+1], bLine[i+1] = aLine[i], bLine[i]
+		if edit.Op != Insert {
+			aLine[i+1]++
+		}
+		if edit.Op != Delete {
+			bLine[i+1]++
+		}
+	}
+
+	for i := 0; i < len(edits); {
+		for i < len(edits) && edits[i].Op == Equal {
+			i++
+		}
+		if i == len(edits) {
+			break
+		}
+
+		// Extend the hunk over changes separated by little enough context to share it
+		start, end := max(i-context, 0), i
+		for {
+			for end < len(edits) && edits[end].Op != Equal {
+				end++
+			}
+			next := end
+			for next < len(edits) && edits[next].Op == Equal {
+				next++
+			}
+			if next < len(edits) && next-end <= 2*context {
+				end = next
+				continue
+			}
+			end = min(end+context, len(edits))
+			break
+		}
+
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(aLine[start], aLine[end]), hunkRange(bLine[start], bLine[end]))
+		for _, edit := range edits[start:end] {
+			prefix := " "
+			switch edit.Op {
+			case Insert:
+				prefix = "+"
+			case Delete:
+				prefix = "-"
+			}
+			out.WriteString(prefix + edit.Text)
+			if !strings.HasSuffix(edit.Text, "\n") {
+				out.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		i = end
+	}
+	return out.String()
+}
+
+// WordDiff renders edits inline, marking deletions as [-text-] and insertions as {+text+}
+func WordDiff(edits []Edit) string {
+	var out strings.Builder
+	for _, edit := range edits {
+		switch edit.Op {
+		case Insert:
+			out.WriteString("{+" + edit.Text + "+}")
+		case Delete:
+			out.WriteString("[-" + edit.Text + "-]")
+		default:
+			out.WriteString(edit.Text)
+		}
+	}
+	return out.String()
+}
+
+// hunkRange formats the start,count of a hunk covering lines (from, to]
+func hunkRange(from, to int) string {
+	count := to - from
+	start := from + 1
+	if count == 0 {
+		start = from // An empty range names the line before it
+	}
+	if count == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// splitLines splits text into lines, each keeping its newline
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// splitWords splits text into alternating runs of whitespace and non-whitespace
+func splitWords(text string) []string {
+	var words []string
+	start, space := 0, false
+	for i, r := range text {
+		if i > start && unicode.IsSpace(r) != space {
+			words = append(words, text[start:i])
+			start = i
+		}
+		space = unicode.IsSpace(r)
+	}
+	if start < len(text) {
+		words = append(words, text[start:])
+	}
+	return words
+}
+
+// merge joins adjacent edits of the same kind
+func merge(edits []Edit) []Edit {
+	merged := make([]Edit, 0, len(edits))
+	for _, edit := range edits {
+		if n := len(merged); n > 0 && merged[n-1].Op == edit.Op {
+			merged[n-1].Text += edit.Text
+			continue
+		}
+		merged = append(merged, edit)
+	}
+	return merged
+}
+
+// tokens diffs two token sequences, one edit per token, skipping their common prefix and suffix
+func tokens(a, b []string) []Edit {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	edits := make([]Edit, 0, len(a)+len(b))
+	for _, token := range a[:prefix] {
+		edits = append(edits, Edit{Equal, token})
+	}
+	edits = append(edits, myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, token := range a[len(a)-suffix:] {
+		edits = append(edits, Edit{Equal, token})
+	}
+	return edits
+}
+
+// myers finds a shortest edit script with Myers' O(ND) algorithm. It works in linear space, splitting
+// the inputs at the middle snake of their edit path and recursing on both halves.
+func myers(a, b []string) []Edit {
+	if len(a) == 0 || len(b) == 0 {
+		return replace(a, b)
+	}
+
+	d := &differ{a: a, b: b, offset: (len(a)+len(b)+1)/2 + 1}
+	d.forward, d.backward = make([]int, 2*d.offset+1), make([]int, 2*d.offset+1)
+	x, y, u, v, ok := d.middleSnake(0, len(a), 0, len(b), maxEditDistance)
+	if !ok {
+		return replace(a, b)
+	}
+	d.edits = make([]Edit, 0, len(a)+len(b))
+	d.diff(0, x, 0, y)
+	d.equal(x, u)
+	d.diff(u, len(a), v, len(b))
+	return d.edits
+}
+
+// differ holds the state of a linear-space Myers diff
+type differ struct {
+	a, b              []string
+	forward, backward []int // Furthest x reached on each diagonal from either end, indexed by diagonal + offset
+	offset            int
+	edits             []Edit
+}
+
+// diff appends the edits turning a[a0:a1] into b[b0:b1]
+func (d *differ) diff(a0, a1, b0, b1 int) {
+	for a0 < a1 && b0 < b1 && d.a[a0] == d.b[b0] {
+		d.equal(a0, a0+1)
+		a0, b0 = a0+1, b0+1
+	}
+	suffix := 0
+	for a1-suffix > a0 && b1-suffix > b0 && d.a[a1-1-suffix] == d.b[b1-1-suffix] {
+		suffix++
+	}
+	a1, b1 = a1-suffix, b1-suffix
+
+	if a0 == a1 || b0 == b1 {
+		d.edits = append(d.edits, replace(d.a[a0:a1], d.b[b0:b1])...)
+	} else {
+		x, y, u, v, _ := d.middleSnake(a0, a1, b0, b1, a1-a0+b1-b0)
+		d.diff(a0, x, b0, y)
+		d.equal(x, u)
+		d.diff(u, a1, v, b1)
+	}
+	d.equal(a1, a1+suffix)
+}
+
+// equal appends a[from:to] as kept
+func (d *differ) equal(from, to int) {
+	for _, token := range d.a[from:to] {
+		d.edits = append(d.edits, Edit{Equal, token})
+	}
+}
+
+// middleSnake searches a[a0:a1] and b[b0:b1] from both ends at once until the paths meet, returning the
+// snake (x, y) to (u, v) where they do. It gives up if the edit distance is more than limit.
+func (d *differ) middleSnake(a0, a1, b0, b1, limit int) (x, y, u, v int, ok bool) {
+	n, m := a1-a0, b1-b0
+	delta := n - m
+	odd := delta%2 != 0
+	rounds := min((n+m+1)/2, (limit+1)/2)
+
+	// Backward paths run over the reversed inputs, where diagonal k of the forward search is delta - k
+	forward, backward, offset := d.forward, d.backward, d.offset
+	forward[offset+1], backward[offset+1] = 0, 0
+	for round := 0; round <= rounds; round++ {
+		for k := -round; k <= round; k += 2 {
+			var x int
+			if k == -round || (k != round && forward[offset+k-1] < forward[offset+k+1]) {
+				x = forward[offset+k+1] // Move down: insert b[y]
+			} else {
+				x = forward[offset+k-1] + 1 // Move right: delete a[x]
+			}
+			y := x - k
+			startX, startY := x, y
+			for x < n && y < m && d.a[a0+x] == d.b[b0+y] {
+				x, y = x+1, y+1
+			}
+			forward[offset+k] = x
+
+			if back := delta - k; odd && back >= -(round-1) && back <= round-1 && x+backward[offset+back] >= n {
+				return a0 + startX, b0 + startY, a0 + x, b0 + y, true
+			}
+		}
+
+		for k := -round; k <= round; k += 2 {
+			var x int
+			if k == -round || (k != round && backward[offset+k-1] < backward[offset+k+1]) {
+				x = backward[offset+k+1]
+			} else {
+				x = backward[offset+k-1] + 1
+			}
+			y := x - k
+			startX, startY := x, y
+			for x < n && y < m && d.a[a1-1-x] == d.b[b1-1-y] {
+				x, y = x+1, y+1
+			}
+			backward[offset+k] = x
+
+			if ahead := delta - k; !odd && ahead >= -round && ahead <= round && x+forward[offset+ahead] >= n {
+				return a1 - x, b1 - y, a1 - startX, b1 - startY, true
+			}
+		}
+	}
+	return 0, 0, 0, 0, false
+}
+
+// replace deletes all of a and inserts all of b
+func replace(a, b []string) []Edit {
+	edits := make([]Edit, 0, len(a)+len(b))
+	for _, token := range a {
+		edits = append(edits, Edit{Delete, token})
+	}
+	for _, token := range b {
+		edits = append(edits, Edit{Insert, token})
+	}
+	return edits
+}
@@ -0,0 +1,148 @@
+package diff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want []Edit
+	}{
+		{"equal", "a\nb\n", "a\nb\n", []Edit{{Equal, "a\nb\n"}}},
+		{"both empty", "", "", []Edit{}},
+		{"from empty", "", "a\n", []Edit{{Insert, "a\n"}}},
+		{"to empty", "a\n", "", []Edit{{Delete, "a\n"}}},
+		{"insert", "a\nc\n", "a\nb\nc\n", []Edit{{Equal, "a\n"}, {Insert, "b\n"}, {Equal, "c\n"}}},
+		{"delete", "a\nb\nc\n", "a\nc\n", []Edit{{Equal, "a\n"}, {Delete, "b\n"}, {Equal, "c\n"}}},
+		{"change", "a\nb\nc\n", "a\nx\nc\n", []Edit{{Equal, "a\n"}, {Delete, "b\n"}, {Insert, "x\n"}, {Equal, "c\n"}}},
+		{"missing final newline", "a\nb", "a\nb\n", []Edit{{Equal, "a\n"}, {Delete, "b"}, {Insert, "b\n"}}},
+	}
+	for _, test := range tests {
+		if got := Lines(test.a, test.b); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: Lines(%q, %q) = %v, want %v", test.name, test.a, test.b, got, test.want)
+		}
+	}
+}
+
+func TestWords(t *testing.T) {
+	edits := Words("the quick fox", "the slow fox")
+	want := []Edit{{Equal, "the "}, {Delete, "quick"}, {Insert, "slow"}, {Equal, " fox"}}
+	if !reflect.DeepEqual(edits, want) {
+		t.Errorf("Words = %v, want %v", edits, want)
+	}
+	if inserted, deleted := Count(edits); inserted != 4 || deleted != 5 {
+		t.Errorf("Count = %d, %d, want 4, 5", inserted, deleted)
+	}
+	if got := WordDiff(edits); got != "the [-quick-]{+slow+} fox" {
+		t.Errorf("WordDiff = %q", got)
+	}
+}
+
+// TestShortestEdits checks the edit scripts against the edit distance found by dynamic programming
+func TestShortestEdits(t *testing.T) {
+	tests := []struct{ a, b string }{
+		{"abcabba", "cbabac"},
+		{"abc", "cba"},
+		{"aaaa", "aa"},
+		{"abab", "baba"},
+		{"xaxbxc", "abc"},
+		{"abcdefgh", "hgfedcba"},
+		{"kitten", "sitting"},
+		{"a", "b"},
+		{"ab", "ba"},
+	}
+	for _, test := range tests {
+		a, b := strings.Split(test.a, ""), strings.Split(test.b, "")
+		edits := tokens(a, b)
+
+		var from, to strings.Builder
+		changes := 0
+		for _, edit := range edits {
+			if edit.Op != Insert {
+				from.WriteString(edit.Text)
+			}
+			if edit.Op != Delete {
+				to.WriteString(edit.Text)
+			}
+			if edit.Op != Equal {
+				changes++
+			}
+		}
+		if from.String() != test.a || to.String() != test.b {
+			t.Errorf("edits of %q -> %q rebuild %q -> %q", test.a, test.b, from.String(), to.String())
+		}
+		if want := editDistance(a, b); changes != want {
+			t.Errorf("edits of %q -> %q make %d changes, want %d", test.a, test.b, changes, want)
+		}
+	}
+}
+
+// editDistance counts the insertions and deletions between two sequences through their longest common subsequence
+func editDistance(a, b []string) int {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	return len(a) + len(b) - 2*lcs[0][0]
+}
+
+func TestTooManyEdits(t *testing.T) {
+	var a, b []string
+	for i := 0; i < maxEditDistance; i++ {
+		a = append(a, "a")
+		b = append(b, "b")
+	}
+	edits := merge(tokens(a, b))
+	if len(edits) != 2 || edits[0].Op != Delete || edits[1].Op != Insert {
+		t.Errorf("diff beyond maxEditDistance = %d edits, want a full replacement", len(edits))
+	}
+}
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    string
+		context int
+		want    string
+	}{
+		{"equal", "a\n", "a\n", 3, ""},
+		{
+			"one hunk", "a\nb\nc\nd\n", "a\nb\nx\nd\n", 1,
+			"--- old\n+++ new\n@@ -2,3 +2,3 @@\n b\n-c\n+x\n d\n",
+		},
+		{
+			"separate hunks", "1\n2\n3\n4\n5\n6\n7\n8\n", "x\n2\n3\n4\n5\n6\n7\ny\n", 1,
+			"--- old\n+++ new\n@@ -1,2 +1,2 @@\n-1\n+x\n 2\n@@ -7,2 +7,2 @@\n 7\n-8\n+y\n",
+		},
+		{
+			"merged hunks", "1\n2\n3\n4\n", "x\n2\n3\ny\n", 1,
+			"--- old\n+++ new\n@@ -1,4 +1,4 @@\n-1\n+x\n 2\n 3\n-4\n+y\n",
+		},
+		{
+			"insert into empty", "", "a\n", 3,
+			"--- old\n+++ new\n@@ -0,0 +1 @@\n+a\n",
+		},
+		{
+			"no newline at end", "a\n", "a\nb", 0,
+			"--- old\n+++ new\n@@ -1,0 +2 @@\n+b\n\\ No newline at end of file\n",
+		},
+	}
+	for _, test := range tests {
+		if got := Unified("old", "new", test.a, test.b, test.context); got != test.want {
+			t.Errorf("%s: Unified =\n%s\nwant\n%s", test.name, got, test.want)
+		}
+	}
+}
@@ -2,6 +2,8 @@ package handlers
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"strconv"
@@ -17,8 +19,8 @@ import (
 // StartWorkerHandler starts a new job
 func StartWorkerHandler(c *gin.Context) {
 	var request struct {
-		URL   string `json:"url"`
-		Depth int    `json:"depth"`
+		URL string `json:"url"`
+		jobs.CrawlOptions
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
@@ -26,7 +28,12 @@ func StartWorkerHandler(c *gin.Context) {
 		return
 	}
 
-	jobID, err := jobs.HireCrawler(request.URL, request.Depth)
+	if err := request.CrawlOptions.Validate(request.URL); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	jobID, err := jobs.HireCrawler(request.URL, request.CrawlOptions)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create job"})
 		return
@@ -79,18 +86,47 @@ func JobStatusHandler(c *gin.Context) {
 		return
 	}
 
-	status, processed, total, err := jobs.GetJobStatus(jobID)
+	status, err := jobs.GetJobStatus(jobID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
 		return
 	}
 
-	c.JSON(http.StatusOK, jobs.JobStatus{
-		JobID:     jobID,
-		Status:    status,
-		Processed: processed,
-		Total:     total,
-	})
+	c.JSON(http.StatusOK, status)
+}
+
+// PauseJobHandler pauses a queued or running job
+func PauseJobHandler(c *gin.Context) {
+	jobControlHandler(c, jobs.PauseJob, "Job paused")
+}
+
+// ResumeJobHandler puts a paused job back in the queue
+func ResumeJobHandler(c *gin.Context) {
+	jobControlHandler(c, jobs.ResumeJob, "Job resumed")
+}
+
+// jobControlHandler applies a state change to the job in the URL
+func jobControlHandler(c *gin.Context, change func(uint64) error, message string) {
+	jobID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job ID"})
+		return
+	}
+
+	if err := change(jobID); err != nil {
+		switch {
+		case errors.Is(err, jobs.ErrJobNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		case errors.Is(err, jobs.ErrJobState):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			log.Printf("❌ Failed to update job %d: %v", jobID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update job"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": message, "job_id": jobID})
 }
 
 // ListJobsHandler returns all jobs
@@ -112,15 +148,275 @@ func JobResultsHandler(c *gin.Context) {
 		return
 	}
 
-	results, err := jobs.GetJobResults(jobID)
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "markdown" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or markdown"})
+		return
+	}
+
+	// Optional ?depth=N restricts results to a single crawl level
+	depth := -1
+	if value := c.Query("depth"); value != "" {
+		depth, err = strconv.Atoi(value)
+		if err != nil || depth < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid depth"})
+			return
+		}
+	}
+
+	results, err := jobs.GetJobResults(jobID, depth)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
 		return
 	}
 
+	if format == "markdown" {
+		c.Data(http.StatusOK, "text/markdown; charset=utf-8", []byte(jobs.MarkdownResults(results)))
+		return
+	}
 	c.JSON(http.StatusOK, results)
 }
 
+// JobSkippedHandler returns the URLs a job skipped and why
+func JobSkippedHandler(c *gin.Context) {
+	jobID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job ID"})
+		return
+	}
+
+	skipped, err := jobs.GetSkippedURLs(jobID)
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+			return
+		}
+		log.Printf("❌ Failed to list skipped URLs of job %d: %v", jobID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch skipped URLs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, skipped)
+}
+
+// JobChangesHandler returns the pages a job found new, changed or gone since the previous crawl of its start URL
+func JobChangesHandler(c *gin.Context) {
+	jobID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job ID"})
+		return
+	}
+
+	changes, err := jobs.GetJobChanges(jobID)
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+			return
+		}
+		log.Printf("❌ Failed to compare job %d: %v", jobID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch changes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, changes)
+}
+
+// LinkReportHandler lists a job's broken link targets and redirect chains with the pages that link to them.
+// Chains with more than ?max_hops= redirects are flagged too long.
+func LinkReportHandler(c *gin.Context) {
+	jobID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job ID"})
+		return
+	}
+
+	maxHops := jobs.DefaultMaxRedirectHops
+	if value := c.Query("max_hops"); value != "" {
+		if maxHops, err = strconv.Atoi(value); err != nil || maxHops < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "max_hops must be a non-negative integer"})
+			return
+		}
+	}
+
+	report, err := jobs.GetLinkReport(jobID, maxHops)
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+			return
+		}
+		log.Printf("❌ Failed to build link report of job %d: %v", jobID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch link report"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// PageReportHandler lists a job's pages with their link metrics, sorted by ?sort= (pagerank, in_degree, out_degree,
+// click_depth, depth or url) in ?order= (desc or asc), optionally only ?orphan=true or ?no_outlinks=true pages
+func PageReportHandler(c *gin.Context) {
+	jobID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job ID"})
+		return
+	}
+
+	query := database.PageMetricsQuery{Sort: c.DefaultQuery("sort", "pagerank"), Limit: jobs.DefaultPageReportLimit}
+	if _, ok := database.PageMetricsSorts[query.Sort]; !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sort must be pagerank, in_degree, out_degree, click_depth, depth or url"})
+		return
+	}
+	switch c.DefaultQuery("order", "desc") {
+	case "desc":
+		query.Descending = true
+	case "asc":
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "order must be asc or desc"})
+		return
+	}
+	for name, flag := range map[string]*bool{"orphan": &query.Orphan, "no_outlinks": &query.NoOutlinks} {
+		if value := c.Query(name); value != "" {
+			if *flag, err = strconv.ParseBool(value); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": name + " must be true or false"})
+				return
+			}
+		}
+	}
+	if value := c.Query("limit"); value != "" {
+		if query.Limit, err = strconv.Atoi(value); err != nil || query.Limit < 1 || query.Limit > jobs.MaxPageReportLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("limit must be between 1 and %d", jobs.MaxPageReportLimit)})
+			return
+		}
+	}
+	if value := c.Query("offset"); value != "" {
+		if query.Offset, err = strconv.Atoi(value); err != nil || query.Offset < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "offset must be a non-negative integer"})
+			return
+		}
+	}
+
+	report, err := jobs.GetPageReport(jobID, query)
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+			return
+		}
+		log.Printf("❌ Failed to build page report of job %d: %v", jobID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch page report"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// LinkGraphHandler exports the link graph of a job as JSON, a CSV edge list, GraphML or DOT (?format=)
+func LinkGraphHandler(c *gin.Context) {
+	jobID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job ID"})
+		return
+	}
+
+	format := c.DefaultQuery("format", jobs.GraphJSON)
+	switch format {
+	case jobs.GraphJSON, jobs.GraphCSV, jobs.GraphGraphML, jobs.GraphDOT:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json, csv, graphml or dot"})
+		return
+	}
+
+	graph, err := jobs.GetLinkGraph(jobID)
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+			return
+		}
+		log.Printf("❌ Failed to build link graph of job %d: %v", jobID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch link graph"})
+		return
+	}
+
+	switch format {
+	case jobs.GraphCSV:
+		edges, err := graph.CSV()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to write link graph"})
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="job-%d-links.csv"`, jobID))
+		c.Data(http.StatusOK, "text/csv; charset=utf-8", []byte(edges))
+	case jobs.GraphGraphML:
+		c.Data(http.StatusOK, "application/graphml+xml; charset=utf-8", []byte(graph.GraphML()))
+	case jobs.GraphDOT:
+		c.Data(http.StatusOK, "text/vnd.graphviz; charset=utf-8", []byte(graph.DOT()))
+	default:
+		c.JSON(http.StatusOK, graph)
+	}
+}
+
+// PageDiffHandler compares stored pages: every page two jobs stored (?from_job=&to_job=), one URL
+// in two jobs (&url=), or the copies of a URL stored at two times (?url=&from=&to=, RFC 3339)
+func PageDiffHandler(c *gin.Context) {
+	query := jobs.DiffQuery{URL: c.Query("url")}
+
+	switch granularity := c.DefaultQuery("granularity", "line"); granularity {
+	case "line":
+	case "word":
+		query.Words = true
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "granularity must be line or word"})
+		return
+	}
+
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "unified" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or unified"})
+		return
+	}
+
+	var err error
+	switch {
+	case c.Query("from_job") != "" || c.Query("to_job") != "":
+		query.FromJob, err = strconv.ParseUint(c.Query("from_job"), 10, 64)
+		if err == nil {
+			query.ToJob, err = strconv.ParseUint(c.Query("to_job"), 10, 64)
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from_job and to_job must both be job IDs"})
+			return
+		}
+	case query.URL != "":
+		query.From, err = time.Parse(time.RFC3339, c.Query("from"))
+		if err == nil {
+			query.To, err = time.Parse(time.RFC3339, c.Query("to"))
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from and to must both be RFC 3339 timestamps"})
+			return
+		}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Either from_job and to_job, or url with from and to, are required"})
+		return
+	}
+
+	diffs, err := jobs.DiffPages(query)
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Page not found"})
+			return
+		}
+		log.Printf("❌ Failed to diff pages: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to diff pages"})
+		return
+	}
+
+	if format == "unified" {
+		c.String(http.StatusOK, jobs.UnifiedDiff(diffs, query.Words))
+		return
+	}
+	c.JSON(http.StatusOK, diffs)
+}
+
 // DeleteJobHandler removes a job and its associated data
 func DeleteJobHandler(c *gin.Context) {
 	jobID, err := strconv.ParseUint(c.Param("id"), 10, 64)
@@ -129,10 +425,8 @@ func DeleteJobHandler(c *gin.Context) {
 		return
 	}
 
-	// Check if job is running and cancel if necessary
-	if worker, exists := jobs.GetJob(jobID); exists {
-		worker.Cancel()       // Stop the worker
-		jobs.RemoveJob(jobID) // Remove after canceling
+	// Stop the job if it is running or still queued
+	if jobs.CancelJob(jobID) {
 		log.Printf("🛑 Job %d canceled and removed", jobID)
 	}
 
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"worker/jobs"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateScheduleHandler creates a recurring crawl
+func CreateScheduleHandler(c *gin.Context) {
+	var request jobs.ScheduleRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	if err := request.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	schedule, err := jobs.CreateSchedule(request)
+	if err != nil {
+		log.Printf("❌ Failed to create schedule: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create schedule"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, schedule)
+}
+
+// ListSchedulesHandler returns all schedules
+func ListSchedulesHandler(c *gin.Context) {
+	schedules, err := jobs.ListSchedules()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch schedules"})
+		return
+	}
+
+	c.JSON(http.StatusOK, schedules)
+}
+
+// GetScheduleHandler returns a schedule and its next run time
+func GetScheduleHandler(c *gin.Context) {
+	scheduleID, ok := scheduleParam(c)
+	if !ok {
+		return
+	}
+
+	schedule, err := jobs.GetSchedule(scheduleID)
+	if err != nil {
+		scheduleErrorResponse(c, scheduleID, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, schedule)
+}
+
+// UpdateScheduleHandler replaces a schedule's definition
+func UpdateScheduleHandler(c *gin.Context) {
+	scheduleID, ok := scheduleParam(c)
+	if !ok {
+		return
+	}
+
+	var request jobs.ScheduleRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	if err := request.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	schedule, err := jobs.UpdateSchedule(scheduleID, request)
+	if err != nil {
+		scheduleErrorResponse(c, scheduleID, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, schedule)
+}
+
+// DeleteScheduleHandler removes a schedule, keeping the jobs it queued
+func DeleteScheduleHandler(c *gin.Context) {
+	scheduleID, ok := scheduleParam(c)
+	if !ok {
+		return
+	}
+
+	if err := jobs.DeleteSchedule(scheduleID); err != nil {
+		scheduleErrorResponse(c, scheduleID, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Schedule deleted successfully", "schedule_id": scheduleID})
+}
+
+// ScheduleRunsHandler returns the jobs a schedule queued, newest first
+func ScheduleRunsHandler(c *gin.Context) {
+	scheduleID, ok := scheduleParam(c)
+	if !ok {
+		return
+	}
+
+	runs, err := jobs.ScheduleRuns(scheduleID)
+	if err != nil {
+		scheduleErrorResponse(c, scheduleID, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, runs)
+}
+
+// scheduleParam parses the schedule ID in the URL, answering 400 if it is invalid
+func scheduleParam(c *gin.Context) (uint64, bool) {
+	scheduleID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid schedule ID"})
+		return 0, false
+	}
+	return scheduleID, true
+}
+
+// scheduleErrorResponse answers 404 for missing schedules and 500 otherwise
+func scheduleErrorResponse(c *gin.Context, scheduleID uint64, err error) {
+	if errors.Is(err, jobs.ErrScheduleNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Schedule not found"})
+		return
+	}
+	log.Printf("❌ Failed to access schedule %d: %v", scheduleID, err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to access schedule"})
+}
@@ -0,0 +1,187 @@
+package jobs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSchedule is a parsed five-field cron expression: minute, hour, day of month, month and day of week
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64 // Bit n is set if value n matches
+	domAny, dowAny                bool   // The field starts with "*", so only the other day field restricts days
+}
+
+// cronField describes the values one field of a cron expression accepts
+type cronField struct {
+	name     string
+	min, max int
+	names    []string // Aliases for min, min+1, ... (JAN-DEC, SUN-SAT)
+}
+
+var cronFields = []cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: []string{"JAN", "FEB", "MAR", "APR", "MAY", "JUN", "JUL", "AUG", "SEP", "OCT", "NOV", "DEC"}},
+	{name: "day of week", min: 0, max: 7, names: []string{"SUN", "MON", "TUE", "WED", "THU", "FRI", "SAT"}},
+}
+
+// cronMacros are the supported @ shorthands
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// parseCron parses a standard cron expression with lists, ranges, steps and month/weekday names
+func parseCron(expr string) (*cronSchedule, error) {
+	expr = strings.TrimSpace(expr)
+	if macro, ok := cronMacros[strings.ToLower(expr)]; ok {
+		expr = macro
+	}
+
+	parts := strings.Fields(expr)
+	if len(parts) != len(cronFields) {
+		return nil, fmt.Errorf("cron expression must have %d fields, got %d", len(cronFields), len(parts))
+	}
+
+	var bits [5]uint64
+	for i, part := range parts {
+		set, err := cronFields[i].parse(part)
+		if err != nil {
+			return nil, err
+		}
+		bits[i] = set
+	}
+
+	// Sunday may be written as 0 or 7
+	if bits[4]&(1<<7) != 0 {
+		bits[4] |= 1
+	}
+
+	return &cronSchedule{
+		minute: bits[0], hour: bits[1], dom: bits[2], month: bits[3], dow: bits[4],
+		domAny: strings.HasPrefix(parts[2], "*"), dowAny: strings.HasPrefix(parts[4], "*"),
+	}, nil
+}
+
+// parse turns a comma-separated list of values, ranges and steps into a bit set
+func (f cronField) parse(field string) (uint64, error) {
+	var set uint64
+	for _, item := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(item, "/")
+
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepPart)
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step %q in %s field", stepPart, f.name)
+			}
+		}
+
+		var low, high int
+		switch {
+		case rangePart == "*":
+			low, high = f.min, f.max
+		case strings.Contains(rangePart, "-"):
+			lowPart, highPart, _ := strings.Cut(rangePart, "-")
+			var err error
+			if low, err = f.value(lowPart); err != nil {
+				return 0, err
+			}
+			if high, err = f.value(highPart); err != nil {
+				return 0, err
+			}
+			if low > high {
+				return 0, fmt.Errorf("invalid range %q in %s field", rangePart, f.name)
+			}
+		default:
+			var err error
+			if low, err = f.value(rangePart); err != nil {
+				return 0, err
+			}
+			high = low
+			if hasStep {
+				high = f.max // "5/15" means from 5 to the end in steps of 15
+			}
+		}
+
+		for v := low; v <= high; v += step {
+			set |= 1 << uint(v)
+		}
+	}
+	return set, nil
+}
+
+// value parses a single number or name and checks it is in range
+func (f cronField) value(s string) (int, error) {
+	for i, name := range f.names {
+		if strings.EqualFold(s, name) {
+			return f.min + i, nil
+		}
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("invalid value %q in %s field (%d-%d)", s, f.name, f.min, f.max)
+	}
+	return v, nil
+}
+
+// next returns the first time after t the schedule fires, in t's location, or the zero time if it never does
+func (s *cronSchedule) next(t time.Time) time.Time {
+	loc := t.Location()
+
+	// advance moves to a wall-clock time, falling back to the next minute when the
+	// zone maps it to an earlier instant so repeated hours never fire twice
+	advance := func(year int, month time.Month, day, hour, minute int) {
+		if n := time.Date(year, month, day, hour, minute, 0, 0, loc); n.After(t) {
+			t = n
+		} else {
+			t = t.Truncate(time.Minute).Add(time.Minute)
+		}
+	}
+	advance(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute()+1)
+
+	// Impossible dates such as 30 February never match; give up after a few years
+	for limit := t.AddDate(5, 0, 0); t.Before(limit); {
+		switch {
+		case s.month&(1<<uint(t.Month())) == 0:
+			advance(t.Year(), t.Month()+1, 1, 0, 0)
+		case !s.dayMatches(t):
+			advance(t.Year(), t.Month(), t.Day()+1, 0, 0)
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			// Step to the next hour in absolute time; wall-clock arithmetic is ambiguous around clock changes
+			hour := t.Hour() + 1
+			t = t.Add(time.Duration(60-t.Minute()) * time.Minute)
+			// Runs in an hour skipped by a clock change fire as soon as the clocks went forward
+			if hour < 24 && t.Hour() != hour && s.hour&(1<<uint(hour)) != 0 {
+				return t
+			}
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			advance(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute()+1)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// dayMatches applies the cron rule that a day matches if either restricted day field does
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	switch {
+	case s.domAny:
+		return dow
+	case s.dowAny:
+		return dom
+	}
+	return dom || dow
+}
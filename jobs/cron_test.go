@@ -0,0 +1,95 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCron(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantErr bool
+	}{
+		{"* * * * *", false},
+		{"*/15 9-17 * * MON-FRI", false},
+		{"0 0 1,15 jan,jul *", false},
+		{"5/10 * * * *", false},
+		{"0 0 * * 7", false},
+		{"@daily", false},
+		{"@Hourly", false},
+		{"* * * *", true},
+		{"* * * * * *", true},
+		{"60 * * * *", true},
+		{"* 24 * * *", true},
+		{"* * 0 * *", true},
+		{"* * * 13 *", true},
+		{"* * * * 8", true},
+		{"*/0 * * * *", true},
+		{"5-1 * * * *", true},
+		{"* * * FOO *", true},
+		{"@fortnightly", true},
+	}
+	for _, test := range tests {
+		_, err := parseCron(test.expr)
+		if (err != nil) != test.wantErr {
+			t.Errorf("parseCron(%q) error = %v, want error %v", test.expr, err, test.wantErr)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{"every minute", "* * * * *", time.Date(2026, 1, 1, 10, 0, 30, 0, time.UTC), time.Date(2026, 1, 1, 10, 1, 0, 0, time.UTC)},
+		{"strictly after", "0 10 * * *", time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC), time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)},
+		{"step", "*/15 * * * *", time.Date(2026, 1, 1, 10, 16, 0, 0, time.UTC), time.Date(2026, 1, 1, 10, 30, 0, 0, time.UTC)},
+		{"next month", "0 0 1 * *", time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC), time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"next year", "@yearly", time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC), time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"weekday", "0 9 * * MON-FRI", time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC), time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)},
+		{"sunday as 7", "0 0 * * 7", time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC), time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)},
+		{"day of month or week", "0 0 13 * FRI", time.Date(2026, 10, 10, 0, 0, 0, 0, time.UTC), time.Date(2026, 10, 13, 0, 0, 0, 0, time.UTC)},
+		{"leap day", "0 0 29 2 *", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"impossible date", "0 0 30 2 *", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), time.Time{}},
+		{"skipped hour", "30 2 * * *", time.Date(2026, 3, 8, 0, 0, 0, 0, newYork), time.Date(2026, 3, 8, 3, 0, 0, 0, newYork)},
+		{"repeated hour", "30 1 * * *", time.Date(2026, 11, 1, 1, 45, 0, 0, newYork), time.Date(2026, 11, 2, 1, 30, 0, 0, newYork)},
+	}
+	for _, test := range tests {
+		schedule, err := parseCron(test.expr)
+		if err != nil {
+			t.Fatalf("%s: parseCron(%q): %v", test.name, test.expr, err)
+		}
+		if got := schedule.next(test.from); !got.Equal(test.want) {
+			t.Errorf("%s: next(%s) = %s, want %s", test.name, test.from, got, test.want)
+		}
+	}
+}
+
+func TestScheduleRequestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		request ScheduleRequest
+		wantErr bool
+	}{
+		{"valid", ScheduleRequest{Cron: "0 3 * * *", URL: "https://example.com/"}, false},
+		{"with timezone", ScheduleRequest{Cron: "@daily", Timezone: "Europe/Berlin", URL: "https://example.com/"}, false},
+		{"invalid cron", ScheduleRequest{Cron: "0 3 * *", URL: "https://example.com/"}, true},
+		{"never fires", ScheduleRequest{Cron: "0 0 30 2 *", URL: "https://example.com/"}, true},
+		{"unknown timezone", ScheduleRequest{Cron: "@daily", Timezone: "Mars/Olympus", URL: "https://example.com/"}, true},
+		{"invalid url", ScheduleRequest{Cron: "@daily", URL: "example.com"}, true},
+	}
+	for _, test := range tests {
+		err := test.request.Validate()
+		if (err != nil) != test.wantErr {
+			t.Errorf("%s: Validate() error = %v, want error %v", test.name, err, test.wantErr)
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package jobs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+	"worker/database"
+	"worker/diff"
+)
+
+// diffContext is the number of unchanged lines around each hunk of a unified diff
+const diffContext = 3
+
+// volatileMetadata are page metadata keys that differ on every fetch, left out of metadata diffs
+var volatileMetadata = map[string]bool{"timestamp": true, "timings": true}
+
+// DiffQuery selects the stored pages to compare: the pages of two jobs, optionally only one URL,
+// or the latest copies of one URL stored at or before two times
+type DiffQuery struct {
+	URL     string
+	FromJob uint64
+	ToJob   uint64
+	From    time.Time
+	To      time.Time
+	Words   bool // Diff content word by word instead of line by line
+}
+
+// PageDiff compares two stored copies of a page
+type PageDiff struct {
+	URL      string           `json:"url"`
+	From     *PageVersion     `json:"from"` // null if the page was not stored by the older crawl
+	To       *PageVersion     `json:"to"`   // null if the page was not stored by the newer crawl
+	Title    *TitleChange     `json:"title,omitempty"`
+	Content  []diff.Edit      `json:"content"`
+	Inserted int              `json:"inserted"` // Characters inserted into the content
+	Deleted  int              `json:"deleted"`  // Characters deleted from the content
+	Metadata []MetadataChange `json:"metadata"`
+
+	fromContent, toContent string
+}
+
+// PageVersion identifies one stored copy of a page
+type PageVersion struct {
+	JobID     uint64    `json:"job_id"`
+	PageID    uint      `json:"page_id"`
+	CrawledAt time.Time `json:"crawled_at"`
+}
+
+// TitleChange is a page title before and after
+type TitleChange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// MetadataChange is a top-level metadata key whose value differs
+type MetadataChange struct {
+	Key  string      `json:"key"`
+	From interface{} `json:"from"` // null if the key was added
+	To   interface{} `json:"to"`   // null if the key was removed
+}
+
+// DiffPages compares stored copies of pages. Comparing two jobs without a URL returns every page that differs.
+// It returns database.ErrNotFound if a job or, for a single URL, both copies of the page don't exist.
+func DiffPages(query DiffQuery) ([]PageDiff, error) {
+	if query.FromJob != 0 && query.URL == "" {
+		return diffJobs(query)
+	}
+
+	var from, to *database.Page
+	var err error
+	if query.FromJob != 0 {
+		from, err = optionalPage(database.GetPageByURL(query.FromJob, query.URL))
+		if err == nil {
+			to, err = optionalPage(database.GetPageByURL(query.ToJob, query.URL))
+		}
+	} else {
+		from, err = optionalPage(database.GetPageAt(query.URL, query.From))
+		if err == nil {
+			to, err = optionalPage(database.GetPageAt(query.URL, query.To))
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if from == nil && to == nil {
+		return nil, database.ErrNotFound
+	}
+
+	return []PageDiff{diffPage(query.URL, from, to, query.Words)}, nil
+}
+
+// diffJobs compares every page two jobs stored, matched by URL
+func diffJobs(query DiffQuery) ([]PageDiff, error) {
+	byURL := make(map[string][2]*database.Page)
+	for side, jobID := range []uint64{query.FromJob, query.ToJob} {
+		if _, err := database.LookupJob(jobID); err != nil {
+			return nil, err
+		}
+		pages, err := database.GetPages(jobID, -1)
+		if err != nil {
+			return nil, err
+		}
+		for i := range pages {
+			pair := byURL[pages[i].URL]
+			if pair[side] == nil {
+				pair[side] = &pages[i]
+				byURL[pages[i].URL] = pair
+			}
+		}
+	}
+
+	urls := make([]string, 0, len(byURL))
+	for url := range byURL {
+		urls = append(urls, url)
+	}
+	sort.Strings(urls)
+
+	diffs := []PageDiff{}
+	for _, url := range urls {
+		pair := byURL[url]
+		pageDiff := diffPage(url, pair[0], pair[1], query.Words)
+		if pageDiff.changed() {
+			diffs = append(diffs, pageDiff)
+		}
+	}
+	return diffs, nil
+}
+
+// diffPage compares two copies of a page, either of which may be missing
+func diffPage(url string, from, to *database.Page, words bool) PageDiff {
+	pageDiff := PageDiff{URL: url, From: pageVersion(from), To: pageVersion(to)}
+
+	var fromPage, toPage database.Page
+	if from != nil {
+		fromPage = *from
+	}
+	if to != nil {
+		toPage = *to
+	}
+
+	if fromPage.Title != toPage.Title {
+		pageDiff.Title = &TitleChange{From: fromPage.Title, To: toPage.Title}
+	}
+
+	pageDiff.fromContent, pageDiff.toContent = fromPage.Content, toPage.Content
+	if words {
+		pageDiff.Content = diff.Words(fromPage.Content, toPage.Content)
+	} else {
+		pageDiff.Content = diff.Lines(fromPage.Content, toPage.Content)
+	}
+	pageDiff.Inserted, pageDiff.Deleted = diff.Count(pageDiff.Content)
+
+	pageDiff.Metadata = diffMetadata(fromPage.Metadata, toPage.Metadata)
+	return pageDiff
+}
+
+// changed reports whether the copies differ, or only one exists
+func (d PageDiff) changed() bool {
+	return d.From == nil || d.To == nil || d.Title != nil || len(d.Metadata) > 0 || diff.Changed(d.Content)
+}
+
+// diffMetadata compares the top-level keys of two metadata objects
+func diffMetadata(from, to []byte) []MetadataChange {
+	var fromValues, toValues map[string]interface{}
+	_ = json.Unmarshal(from, &fromValues)
+	_ = json.Unmarshal(to, &toValues)
+
+	keys := make(map[string]bool)
+	for key := range fromValues {
+		keys[key] = true
+	}
+	for key := range toValues {
+		keys[key] = true
+	}
+
+	changes := []MetadataChange{}
+	for key := range keys {
+		if volatileMetadata[key] || reflect.DeepEqual(fromValues[key], toValues[key]) {
+			continue
+		}
+		changes = append(changes, MetadataChange{Key: key, From: fromValues[key], To: toValues[key]})
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	return changes
+}
+
+// UnifiedDiff renders page diffs as a patch. Title and metadata changes precede each page's
+// content diff as header lines, which patch tools ignore.
+func UnifiedDiff(diffs []PageDiff, words bool) string {
+	var out strings.Builder
+	for _, d := range diffs {
+		if d.Title != nil {
+			fmt.Fprintf(&out, "title: %q -> %q\n", d.Title.From, d.Title.To)
+		}
+		for _, change := range d.Metadata {
+			fmt.Fprintf(&out, "metadata %s: %s -> %s\n", change.Key, metadataValue(change.From), metadataValue(change.To))
+		}
+
+		fromName, toName := versionName(d.URL, d.From), versionName(d.URL, d.To)
+		if words {
+			if diff.Changed(d.Content) {
+				text := diff.WordDiff(d.Content)
+				if !strings.HasSuffix(text, "\n") {
+					text += "\n"
+				}
+				fmt.Fprintf(&out, "--- %s\n+++ %s\n%s", fromName, toName, text)
+			}
+			continue
+		}
+		out.WriteString(diff.Unified(fromName, toName, d.fromContent, d.toContent, diffContext))
+	}
+	return out.String()
+}
+
+// optionalPage turns a missing page into nil
+func optionalPage(page *database.Page, err error) (*database.Page, error) {
+	if errors.Is(err, database.ErrNotFound) {
+		return nil, nil
+	}
+	return page, err
+}
+
+// pageVersion identifies a stored page, or returns nil for a missing one
+func pageVersion(page *database.Page) *PageVersion {
+	if page == nil {
+		return nil
+	}
+	return &PageVersion{JobID: page.JobID, PageID: page.ID, CrawledAt: page.CreatedAt}
+}
+
+// versionName labels one side of a unified diff
+func versionName(url string, version *PageVersion) string {
+	if version == nil {
+		return "/dev/null"
+	}
+	return fmt.Sprintf("%s\tjob %d %s", url, version.JobID, version.CrawledAt.Format(time.RFC3339))
+}
+
+// metadataValue renders a metadata value as JSON for a unified diff header
+func metadataValue(value interface{}) string {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(encoded)
+}
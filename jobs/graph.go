@@ -0,0 +1,194 @@
+package jobs
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+	"worker/database"
+)
+
+// Link graph export formats
+const (
+	GraphJSON    = "json"
+	GraphCSV     = "csv"
+	GraphGraphML = "graphml"
+	GraphDOT     = "dot"
+)
+
+// LinkGraph is the pages of a job and the links between them
+type LinkGraph struct {
+	JobID uint64      `json:"job_id"`
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+// GraphNode is a crawled page or a link target
+type GraphNode struct {
+	URL      string `json:"url"`
+	Title    string `json:"title,omitempty"`
+	Depth    *int   `json:"depth,omitempty"` // Hop distance of crawled pages
+	Internal bool   `json:"internal"`
+	Crawled  bool   `json:"crawled"`
+}
+
+// GraphEdge is a link from one page to a URL
+type GraphEdge struct {
+	Source    string `json:"source"`
+	Target    string `json:"target"`
+	Anchor    string `json:"anchor"`
+	Nofollow  bool   `json:"nofollow"`
+	Sponsored bool   `json:"sponsored"`
+	UGC       bool   `json:"ugc"`
+}
+
+// GetLinkGraph builds the link graph of a job: a node for every stored page and link target,
+// and an edge for every link
+func GetLinkGraph(jobID uint64) (*LinkGraph, error) {
+	job, err := database.LookupJob(jobID)
+	if err != nil {
+		return nil, err
+	}
+	pages, err := database.GetPageHeaders(jobID)
+	if err != nil {
+		return nil, err
+	}
+	links, err := database.GetLinks(jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	graph := &LinkGraph{JobID: job.ID, Nodes: []GraphNode{}, Edges: make([]GraphEdge, 0, len(links))}
+	index := make(map[string]int)
+	for _, page := range pages {
+		if _, exists := index[page.URL]; exists {
+			continue
+		}
+		depth := page.Depth
+		index[page.URL] = len(graph.Nodes)
+		graph.Nodes = append(graph.Nodes, GraphNode{URL: page.URL, Title: page.Title, Depth: &depth, Internal: true, Crawled: true})
+	}
+	for _, link := range links {
+		if i, exists := index[link.TargetURL]; exists {
+			graph.Nodes[i].Crawled = graph.Nodes[i].Crawled || link.Crawled
+		} else {
+			index[link.TargetURL] = len(graph.Nodes)
+			graph.Nodes = append(graph.Nodes, GraphNode{URL: link.TargetURL, Internal: link.Internal, Crawled: link.Crawled})
+		}
+		graph.Edges = append(graph.Edges, GraphEdge{
+			Source:    link.SourceURL,
+			Target:    link.TargetURL,
+			Anchor:    link.Anchor,
+			Nofollow:  link.Nofollow,
+			Sponsored: link.Sponsored,
+			UGC:       link.UGC,
+		})
+	}
+	return graph, nil
+}
+
+// CSV renders the graph as an edge list with a header row
+func (g *LinkGraph) CSV() (string, error) {
+	var out strings.Builder
+	writer := csv.NewWriter(&out)
+	_ = writer.Write([]string{"source", "target", "anchor", "nofollow", "sponsored", "ugc"})
+	for _, edge := range g.Edges {
+		_ = writer.Write([]string{
+			edge.Source, edge.Target, edge.Anchor,
+			strconv.FormatBool(edge.Nofollow), strconv.FormatBool(edge.Sponsored), strconv.FormatBool(edge.UGC),
+		})
+	}
+	writer.Flush()
+	return out.String(), writer.Error()
+}
+
+// GraphML renders the graph as a directed GraphML document, with page attributes as node data
+// and rel attributes as edge data
+func (g *LinkGraph) GraphML() string {
+	var out strings.Builder
+	out.WriteString(xml.Header)
+	out.WriteString(`<graphml xmlns="http://graphml.graphdrawing.org/xmlns">` + "\n")
+	for _, key := range []struct{ id, target, kind string }{
+		{"url", "node", "string"}, {"title", "node", "string"}, {"depth", "node", "int"},
+		{"internal", "node", "boolean"}, {"crawled", "node", "boolean"},
+		{"anchor", "edge", "string"}, {"nofollow", "edge", "boolean"}, {"sponsored", "edge", "boolean"}, {"ugc", "edge", "boolean"},
+	} {
+		fmt.Fprintf(&out, `  <key id="%s" for="%s" attr.name="%s" attr.type="%s"/>`+"\n", key.id, key.target, key.id, key.kind)
+	}
+	fmt.Fprintf(&out, `  <graph id="job-%d" edgedefault="directed">`+"\n", g.JobID)
+
+	ids := make(map[string]string, len(g.Nodes))
+	for i, node := range g.Nodes {
+		ids[node.URL] = fmt.Sprintf("n%d", i)
+		fmt.Fprintf(&out, `    <node id="n%d">`, i)
+		writeGraphMLData(&out, "url", node.URL)
+		if node.Title != "" {
+			writeGraphMLData(&out, "title", node.Title)
+		}
+		if node.Depth != nil {
+			writeGraphMLData(&out, "depth", strconv.Itoa(*node.Depth))
+		}
+		writeGraphMLData(&out, "internal", strconv.FormatBool(node.Internal))
+		writeGraphMLData(&out, "crawled", strconv.FormatBool(node.Crawled))
+		out.WriteString("</node>\n")
+	}
+	for i, edge := range g.Edges {
+		fmt.Fprintf(&out, `    <edge id="e%d" source="%s" target="%s">`, i, ids[edge.Source], ids[edge.Target])
+		writeGraphMLData(&out, "anchor", edge.Anchor)
+		writeGraphMLData(&out, "nofollow", strconv.FormatBool(edge.Nofollow))
+		writeGraphMLData(&out, "sponsored", strconv.FormatBool(edge.Sponsored))
+		writeGraphMLData(&out, "ugc", strconv.FormatBool(edge.UGC))
+		out.WriteString("</edge>\n")
+	}
+	out.WriteString("  </graph>\n</graphml>\n")
+	return out.String()
+}
+
+// writeGraphMLData writes an escaped <data> element
+func writeGraphMLData(out *strings.Builder, key, value string) {
+	fmt.Fprintf(out, `<data key="%s">`, key)
+	_ = xml.EscapeText(out, []byte(value))
+	out.WriteString("</data>")
+}
+
+// DOT renders the graph for Graphviz. External targets are dashed and links with rel attributes
+// carry them as a rel attribute.
+func (g *LinkGraph) DOT() string {
+	var out strings.Builder
+	fmt.Fprintf(&out, "digraph %s {\n", dotQuote(fmt.Sprintf("job %d", g.JobID)))
+	for _, node := range g.Nodes {
+		label := node.Title
+		if label == "" {
+			label = node.URL
+		}
+		attributes := []string{"label=" + dotQuote(label), fmt.Sprintf("crawled=%t", node.Crawled)}
+		if !node.Internal {
+			attributes = append(attributes, "style=dashed")
+		}
+		fmt.Fprintf(&out, "  %s [%s];\n", dotQuote(node.URL), strings.Join(attributes, ", "))
+	}
+	for _, edge := range g.Edges {
+		attributes := []string{"anchor=" + dotQuote(edge.Anchor)}
+		var rel []string
+		for _, flag := range []struct {
+			set  bool
+			name string
+		}{{edge.Nofollow, "nofollow"}, {edge.Sponsored, "sponsored"}, {edge.UGC, "ugc"}} {
+			if flag.set {
+				rel = append(rel, flag.name)
+			}
+		}
+		if len(rel) > 0 {
+			attributes = append(attributes, "rel="+dotQuote(strings.Join(rel, " ")), "style=dotted")
+		}
+		fmt.Fprintf(&out, "  %s -> %s [%s];\n", dotQuote(edge.Source), dotQuote(edge.Target), strings.Join(attributes, ", "))
+	}
+	out.WriteString("}\n")
+	return out.String()
+}
+
+// dotQuote quotes a DOT identifier
+func dotQuote(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", "").Replace(value) + `"`
+}
@@ -1,6 +1,12 @@
 package jobs
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
 	"sync"
 	"time"
 	"worker/database"
@@ -10,50 +16,152 @@ import (
 // JobManager manages active workers
 var activeWorkers sync.Map // map[uint]*worker.Worker
 
-// HireCrawler starts a new crawling job
-func HireCrawler(url string, depth int) (uint64, error) {
+// cancelTimeout bounds how long CancelJob waits for a worker to stop
+const cancelTimeout = 30 * time.Second
+
+var (
+	ErrJobNotFound = errors.New("job not found")
+	ErrJobState    = errors.New("job cannot be changed in its current state")
+)
+
+// HireCrawler queues a new crawling job; the first node with a free slot runs it
+func HireCrawler(url string, options CrawlOptions) (uint64, error) {
+	if err := options.Validate(url); err != nil {
+		return 0, err
+	}
+
 	// Create a new job in the database
-	job, err := database.CreateJob(1) // Default priority
+	job, err := database.CreateJob(url, options.JobPriority(), options, "")
 	if err != nil {
 		return 0, err
 	}
 
-	config := worker.WorkerConfig{
-		MaxLinks:     64,
-		RequestDelay: 0 * time.Second,
-		CustomHeaders: map[string]string{
-			"User-Agent": "ProRobot/1.0",
-		},
+	queue.dispatch()
+	return job.ID, nil
+}
+
+// HireCrawlerWithProgress queues a job that runs on this node, reporting progress to cb.
+// Canceling ctx cancels the job; the returned channel is closed when it finishes or is canceled.
+func HireCrawlerWithProgress(ctx context.Context, url string, options CrawlOptions, cb worker.WorkerStatusCallback) (uint64, <-chan struct{}, error) {
+	if err := options.Validate(url); err != nil {
+		return 0, nil, err
+	}
+	return queue.pin(ctx, url, options, cb)
+}
+
+// restoreWorker rebuilds a job's worker from its stored options, failing jobs that have none
+func restoreWorker(job *database.Job) (*worker.Worker, bool) {
+	var options CrawlOptions
+	if job.URL == "" || json.Unmarshal(job.Options, &options) != nil {
+		log.Printf("⚠️ Job %d has no stored options and cannot be run", job.ID)
+		database.UpdateJobStatus(job.ID, "failed")
+		return nil, false
+	}
+	return worker.NewWorker(job.ID, job.URL, options.WorkerConfig(), nil), true
+}
+
+// CancelJob stops a job running or waiting on this node and marks it canceled, reporting whether it was found.
+// It returns once the worker has stopped, so nothing is written for the job afterwards.
+func CancelJob(jobID uint64) bool {
+	if Dequeue(jobID) {
+		if _, err := database.FinishJob(jobID, "canceled"); err != nil {
+			log.Printf("❌ Failed to cancel job %d: %v", jobID, err)
+		}
+		return true
 	}
 
-	newWorker := worker.NewWorker(job.ID, url, config, nil)
-	StoreJob(job.ID, newWorker)
+	w, exists := GetJob(jobID)
+	if !exists {
+		return false
+	}
+	w.Cancel()
+	select {
+	case <-w.Done():
+	case <-time.After(cancelTimeout):
+		log.Printf("⚠️ Job %d did not stop within %s", jobID, cancelTimeout)
+	}
+	RemoveJob(jobID, w)
+	return true
+}
 
-	// Run the worker in a goroutine
-	go func() {
-		newWorker.Start()
-		RemoveJob(job.ID)
-	}()
+// PauseJob stops a queued or running job, keeping its frontier so it can be resumed later.
+// In-flight fetches are allowed to finish.
+func PauseJob(jobID uint64) error {
+	previous, err := database.TransitionJob(jobID, []string{"queued", "in_progress"}, map[string]interface{}{"status": "paused"})
+	if err != nil {
+		return jobError(err)
+	}
+	if previous != "queued" && previous != "in_progress" {
+		return fmt.Errorf("%w: job is %s", ErrJobState, previous)
+	}
 
-	return job.ID, nil
+	// Jobs running on other nodes stop at their next heartbeat
+	Dequeue(jobID)
+	if w, exists := GetJob(jobID); exists {
+		w.Pause()
+	}
+	return nil
+}
+
+// ResumeJob puts a paused job back in the queue; it continues from its frontier on whichever node claims it.
+// A job paused on this node is re-queued only once its worker has finished its in-flight fetches.
+func ResumeJob(jobID uint64) error {
+	// Until then its in-flight URLs are still queued in the frontier and would be crawled twice
+	if w, exists := GetJob(jobID); exists && w.Paused() {
+		select {
+		case <-w.Done():
+		case <-time.After(cancelTimeout):
+			return fmt.Errorf("%w: job is still finishing its in-flight fetches", ErrJobState)
+		}
+	}
+
+	previous, err := database.TransitionJob(jobID, []string{"paused"}, map[string]interface{}{
+		"status":           "queued",
+		"lease_owner":      "",
+		"lease_expires_at": nil,
+	})
+	if err != nil {
+		return jobError(err)
+	}
+	if previous != "paused" {
+		return fmt.Errorf("%w: job is %s", ErrJobState, previous)
+	}
+
+	queue.dispatch()
+	return nil
+}
+
+// jobError maps a missing job onto ErrJobNotFound
+func jobError(err error) error {
+	if errors.Is(err, database.ErrNotFound) {
+		return ErrJobNotFound
+	}
+	return err
 }
 
 // GetJobStatus fetches the status of an active or completed job
-func GetJobStatus(jobID uint64) (string, int, int, error) {
+func GetJobStatus(jobID uint64) (*JobStatus, error) {
 	// Check if the job is still active
 	if val, exists := activeWorkers.Load(jobID); exists {
 		cr := val.(*worker.Worker)
-		processed, total := cr.GetStatus()
-		return "running", processed, total, nil
+		state := "running"
+		if cr.Paused() {
+			state = "paused" // Finishing its in-flight fetches
+		}
+		status := newJobStatus(jobID, state, cr.GetProgress())
+		status.Node = NodeID()
+		scope := cr.Scope()
+		status.Scope = &scope
+		return &status, nil
 	}
 
 	// If not active, fetch from database
 	job, err := database.GetJob(jobID)
 	if err != nil {
-		return "", 0, 0, err
+		return nil, err
 	}
 
-	return job.Status, len(job.Pages), len(job.Pages), nil
+	return dbJobStatus(job), nil
 }
 
 // ListJobs returns all active and completed jobs
@@ -65,14 +173,13 @@ func ListJobs() ([]JobStatus, error) {
 	activeWorkers.Range(func(key, value interface{}) bool {
 		jobID := key.(uint64)
 		cr := value.(*worker.Worker)
-		processed, total := cr.GetStatus()
-
-		jobs = append(jobs, JobStatus{
-			JobID:     jobID,
-			Status:    "in_progress",
-			Processed: processed,
-			Total:     total,
-		})
+		state := "in_progress"
+		if cr.Paused() {
+			state = "paused"
+		}
+		status := newJobStatus(jobID, state, cr.GetProgress())
+		status.Node = NodeID()
+		jobs = append(jobs, status)
 
 		activeJobIDs[jobID] = true
 		return true
@@ -88,24 +195,113 @@ func ListJobs() ([]JobStatus, error) {
 		if activeJobIDs[job.ID] {
 			continue
 		}
-		jobs = append(jobs, JobStatus{
-			JobID:     job.ID,
-			Status:    job.Status,
-			Processed: len(job.Pages),
-			Total:     len(job.Pages),
-		})
+		jobs = append(jobs, *dbJobStatus(&job))
 	}
 
 	return jobs, nil
 }
 
-// GetJobResults returns the crawled pages for a completed job
-func GetJobResults(jobID uint64) ([]database.Page, error) {
-	job, err := database.GetJob(jobID)
+// GetJobResults returns the crawled pages and failed URLs of a job, with pages optionally filtered by depth (depth < 0 = all)
+func GetJobResults(jobID uint64, depth int) (*JobResults, error) {
+	if _, err := database.LookupJob(jobID); err != nil {
+		return nil, err
+	}
+
+	pages, err := database.GetPages(jobID, depth)
+	if err != nil {
+		return nil, err
+	}
+
+	failures, err := database.GetFailures(jobID)
 	if err != nil {
 		return nil, err
 	}
-	return job.Pages, nil
+
+	return &JobResults{Pages: pages, Failures: failures}, nil
+}
+
+// skippedClasses are the fetch failures of URLs skipped on purpose rather than broken
+var skippedClasses = []string{string(worker.ErrorRobotsBlocked), string(worker.ErrorTooLarge), string(worker.ErrorContentType)}
+
+// GetSkippedURLs returns the final attempts of the URLs a job deliberately did not crawl
+func GetSkippedURLs(jobID uint64) ([]database.FetchAttempt, error) {
+	if _, err := database.LookupJob(jobID); err != nil {
+		return nil, err
+	}
+	return database.GetSkippedAttempts(jobID, skippedClasses)
+}
+
+// MarkdownResults joins the Markdown of a job's pages into one document, introducing each page
+// with an HTML comment holding its URL and title
+func MarkdownResults(results *JobResults) string {
+	var out strings.Builder
+	for i, page := range results.Pages {
+		if i > 0 {
+			out.WriteString("\n\n")
+		}
+		fmt.Fprintf(&out, "<!-- url: %s title: %s -->\n\n", commentText(page.URL), commentText(strings.TrimSpace(page.Title)))
+		out.WriteString(page.Markdown)
+	}
+	if out.Len() > 0 {
+		out.WriteString("\n")
+	}
+	return out.String()
+}
+
+// commentText makes text safe inside an HTML comment on one line. Splitting "--" can leave new pairs,
+// as in "--->", so it repeats until none are left.
+func commentText(text string) string {
+	text = strings.ReplaceAll(text, "\n", " ")
+	for strings.Contains(text, "--") {
+		text = strings.ReplaceAll(text, "--", "- -")
+	}
+	return text
+}
+
+// GetJobChanges compares a job's pages with the previous completed crawl of the same start URL
+func GetJobChanges(jobID uint64) (*JobChanges, error) {
+	job, err := database.LookupJob(jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	previous, err := database.PreviousJob(jobID, job.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	counts, err := database.CountPageChanges(jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	pages, err := database.GetPageChanges(jobID, []string{database.ChangeNew, database.ChangeChanged, database.ChangeGone})
+	if err != nil {
+		return nil, err
+	}
+
+	changes := &JobChanges{
+		JobID:     jobID,
+		New:       []PageChange{},
+		Changed:   []PageChange{},
+		Gone:      []PageChange{},
+		Unchanged: counts[database.ChangeUnchanged],
+	}
+	if previous != 0 {
+		changes.PreviousJobID = &previous
+	}
+	for _, page := range pages {
+		change := PageChange{URL: page.URL, Title: page.Title, Depth: page.Depth, ContentHash: page.ContentHash}
+		switch page.ChangeStatus {
+		case database.ChangeNew:
+			changes.New = append(changes.New, change)
+		case database.ChangeChanged:
+			changes.Changed = append(changes.Changed, change)
+		case database.ChangeGone:
+			changes.Gone = append(changes.Gone, change)
+		}
+	}
+	return changes, nil
 }
 
 // StoreJob registers a new worker
@@ -122,15 +318,87 @@ func GetJob(jobID uint64) (*worker.Worker, bool) {
 	return val.(*worker.Worker), true
 }
 
-// RemoveJob removes a completed or canceled job
-func RemoveJob(jobID uint64) {
-	activeWorkers.Delete(jobID)
+// RemoveJob removes a completed or canceled job, unless a later run of the job has replaced its worker
+func RemoveJob(jobID uint64, w *worker.Worker) {
+	activeWorkers.CompareAndDelete(jobID, w)
 }
 
 // JobStatus struct for API response
 type JobStatus struct {
-	JobID     uint64 `json:"job_id"`
-	Status    string `json:"status"`
-	Processed int    `json:"processed"`
-	Total     int    `json:"total"`
+	JobID     uint64  `json:"job_id"`
+	Status    string  `json:"status"`
+	Priority  int     `json:"priority,omitempty"`
+	Node      string  `json:"node,omitempty"`           // Node running the job
+	Position  int     `json:"queue_position,omitempty"` // 1-based place in the job queue while queued
+	Schedule  *uint64 `json:"schedule_id,omitempty"`    // Schedule that queued the job
+	Processed int     `json:"processed"`
+	Total     int     `json:"total"`
+	Queued    int     `json:"queued"`    // URLs waiting in the frontier
+	InFlight  int     `json:"in_flight"` // URLs currently being fetched
+	Done      int     `json:"done"`      // URLs fully processed
+
+	Scope   *worker.EffectiveScope `json:"scope,omitempty"`   // Scope applied by a running job and what it rejected
+	Summary json.RawMessage        `json:"summary,omitempty"` // What a completed or canceled job got done
+}
+
+// JobResults struct for API response
+type JobResults struct {
+	Pages    []database.Page         `json:"pages"`
+	Failures []database.FetchAttempt `json:"failures"` // Final attempt of every URL that could not be crawled
+}
+
+// JobChanges struct for API response
+type JobChanges struct {
+	JobID         uint64       `json:"job_id"`
+	PreviousJobID *uint64      `json:"previous_job_id"` // Crawl the job was compared with, null for a first crawl
+	New           []PageChange `json:"new"`
+	Changed       []PageChange `json:"changed"`
+	Gone          []PageChange `json:"gone"`
+	Unchanged     int64        `json:"unchanged"` // Pages with the same content, only counted
+}
+
+// PageChange is a page that differs from the previous crawl
+type PageChange struct {
+	URL         string `json:"url"`
+	Title       string `json:"title"`
+	Depth       int    `json:"depth"`
+	ContentHash string `json:"content_hash"`
+}
+
+// newJobStatus builds the API status of a running worker
+func newJobStatus(jobID uint64, status string, progress worker.WorkerProgress) JobStatus {
+	return JobStatus{
+		JobID:     jobID,
+		Status:    status,
+		Processed: progress.Processed,
+		Total:     progress.Total,
+		Queued:    progress.Queued,
+		InFlight:  progress.InFlight,
+		Done:      progress.Done,
+	}
+}
+
+// dbJobStatus builds the API status of a job that is not running on this node
+func dbJobStatus(job *database.Job) *JobStatus {
+	status := &JobStatus{
+		JobID:     job.ID,
+		Status:    job.Status,
+		Priority:  job.Priority,
+		Node:      job.LeaseOwner,
+		Schedule:  job.ScheduleID,
+		Processed: job.PageCount,
+		Total:     job.PageCount,
+		Done:      job.PageCount,
+	}
+	if len(job.Summary) > 0 {
+		status.Summary = json.RawMessage(job.Summary)
+	}
+	if job.Status == "queued" {
+		position, err := database.QueuePosition(job)
+		if err != nil {
+			log.Printf("⚠️ Failed to get queue position of job %d: %v", job.ID, err)
+		}
+		status.Position = position
+	}
+	return status
 }
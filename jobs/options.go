@@ -0,0 +1,209 @@
+package jobs
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"time"
+	"worker/worker"
+)
+
+// CrawlOptions are the per-job settings accepted when a job is created
+type CrawlOptions struct {
+	Priority int `json:"priority"` // 1 = low (default), 2 = medium, 3 = high
+
+	MaxLinks int                 `json:"max_links"` // Maximum number of pages to crawl (default 64)
+	Depth    int                 `json:"depth"`     // Maximum hop distance from the start URL (0 = unlimited)
+	Robots   worker.RobotsPolicy `json:"robots"`    // obey (default), ignore or report-only
+
+	Concurrency int `json:"concurrency"` // Pages fetched at once by the job (default 4)
+
+	RequestDelayMs  int `json:"request_delay_ms"`   // Minimum delay between requests to one host (default 250)
+	MaxConnsPerHost int `json:"max_conns_per_host"` // Concurrent requests per host, shared by all jobs (default 2)
+
+	Sitemaps    bool `json:"sitemaps"`     // Seed the frontier from robots.txt Sitemap: lines and /sitemap.xml
+	SitemapOnly bool `json:"sitemap_only"` // Only crawl sitemap URLs, without following links
+
+	MaxRetries *int `json:"max_retries"` // Retries for transient failures (default 2)
+
+	ConnectTimeoutMs   int               `json:"connect_timeout_ms"`   // TCP connect and TLS handshake timeout (default 10000)
+	ReadTimeoutMs      int               `json:"read_timeout_ms"`      // Time to wait for response headers (default 30000)
+	TimeoutMs          int               `json:"timeout_ms"`           // Whole request including the body (default 60000)
+	ProxyURL           string            `json:"proxy_url"`            // HTTP(S) proxy for every request
+	CABundle           string            `json:"ca_bundle"`            // Extra PEM-encoded root certificates to trust
+	InsecureSkipVerify bool              `json:"insecure_skip_verify"` // Accept any TLS certificate
+	MaxRedirects       *int              `json:"max_redirects"`        // Redirects to follow per request (default 10)
+	Headers            map[string]string `json:"headers"`              // Extra request headers, may override User-Agent
+
+	MaxBodyBytes        int64    `json:"max_body_bytes"`        // Largest response body read (default 10 MiB)
+	AllowedContentTypes []string `json:"allowed_content_types"` // Media types parsed (default text/html, application/xhtml+xml)
+	HeadRequests        bool     `json:"head_requests"`         // Send HEAD first instead of aborting GETs early
+
+	Normalize worker.NormalizeConfig `json:"normalize"` // URL canonicalization rules for deduplication
+	Scope     worker.ScopeConfig     `json:"scope"`     // Host/domain/path scope and include/exclude rules
+
+	Incremental bool `json:"incremental"` // Revisit the previous crawl of the start URL with conditional requests
+
+	ContentMode worker.ContentMode `json:"content_mode"` // main (default) stores the article text and HTML, raw all text of <body>
+
+	Extract worker.ExtractionSchema `json:"extract"` // Structured record extracted from each page with CSS selectors
+
+	CheckExternalLinks bool `json:"check_external_links"` // Request external link targets once after the crawl to find broken ones
+}
+
+const (
+	defaultPriority        = 1
+	maxPriority            = 3
+	defaultMaxLinks        = 64
+	defaultUserAgent       = "ProRobot/1.0"
+	defaultRequestDelay    = 250 * time.Millisecond
+	defaultMaxConnsPerHost = 2
+	defaultMaxRetries      = 2
+	maxRetries             = 10
+	maxConcurrency         = 32
+)
+
+// Validate checks the options before a job is created
+func (o CrawlOptions) Validate(startURL string) error {
+	parsedURL, err := url.Parse(startURL)
+	if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") || parsedURL.Host == "" {
+		return errors.New("url must be an absolute http(s) URL")
+	}
+	if o.Priority < 0 || o.Priority > maxPriority {
+		return fmt.Errorf("priority must be between 1 and %d", maxPriority)
+	}
+	if o.MaxLinks < 0 {
+		return errors.New("max_links must not be negative")
+	}
+	if o.Depth < 0 {
+		return errors.New("depth must not be negative")
+	}
+	if o.Concurrency < 0 || o.Concurrency > maxConcurrency {
+		return fmt.Errorf("concurrency must be between 1 and %d", maxConcurrency)
+	}
+	if !o.Robots.Valid() {
+		return errors.New("robots must be one of obey, ignore or report-only")
+	}
+	if o.RequestDelayMs < 0 {
+		return errors.New("request_delay_ms must not be negative")
+	}
+	if o.MaxConnsPerHost < 0 {
+		return errors.New("max_conns_per_host must not be negative")
+	}
+	if o.MaxRetries != nil && (*o.MaxRetries < 0 || *o.MaxRetries > maxRetries) {
+		return fmt.Errorf("max_retries must be between 0 and %d", maxRetries)
+	}
+	if o.ConnectTimeoutMs < 0 || o.ReadTimeoutMs < 0 || o.TimeoutMs < 0 {
+		return errors.New("timeouts must not be negative")
+	}
+	if o.MaxBodyBytes < 0 {
+		return errors.New("max_body_bytes must not be negative")
+	}
+	for _, contentType := range o.AllowedContentTypes {
+		if _, _, err := mime.ParseMediaType(contentType); err != nil {
+			return fmt.Errorf("invalid content type %q", contentType)
+		}
+	}
+	if !o.ContentMode.Valid() {
+		return errors.New("content_mode must be one of main or raw")
+	}
+	if o.MaxRedirects != nil && *o.MaxRedirects < 0 {
+		return errors.New("max_redirects must not be negative")
+	}
+
+	// Building the client checks the proxy URL and CA bundle
+	if _, err := worker.NewHTTPClient(o.WorkerConfig()); err != nil {
+		return err
+	}
+	if err := worker.ValidateExtraction(o.Extract); err != nil {
+		return err
+	}
+	return worker.ValidateScope(o.Scope, startURL)
+}
+
+// JobPriority returns the queue priority of the job
+func (o CrawlOptions) JobPriority() int {
+	if o.Priority == 0 {
+		return defaultPriority
+	}
+	return o.Priority
+}
+
+// WorkerConfig builds the worker configuration for a job
+func (o CrawlOptions) WorkerConfig() worker.WorkerConfig {
+	robots := o.Robots
+	if robots == "" {
+		robots = worker.RobotsObey
+	}
+
+	delay := defaultRequestDelay
+	if o.RequestDelayMs > 0 {
+		delay = time.Duration(o.RequestDelayMs) * time.Millisecond
+	}
+
+	maxConns := o.MaxConnsPerHost
+	if maxConns == 0 {
+		maxConns = defaultMaxConnsPerHost
+	}
+
+	retries := defaultMaxRetries
+	if o.MaxRetries != nil {
+		retries = *o.MaxRetries
+	}
+
+	maxLinks := o.MaxLinks
+	if maxLinks == 0 {
+		maxLinks = defaultMaxLinks
+	}
+
+	// A max_redirects of 0 means "don't follow", which the worker spells as negative
+	redirects := 0
+	if o.MaxRedirects != nil {
+		redirects = *o.MaxRedirects
+		if redirects == 0 {
+			redirects = -1
+		}
+	}
+
+	headers := map[string]string{
+		"User-Agent": defaultUserAgent,
+	}
+	for key, value := range o.Headers {
+		headers[http.CanonicalHeaderKey(key)] = value
+	}
+
+	return worker.WorkerConfig{
+		MaxLinks:        maxLinks,
+		MaxDepth:        o.Depth,
+		Concurrency:     o.Concurrency, // The worker defaults to 4
+		Robots:          robots,
+		RequestDelay:    delay,
+		MaxConnsPerHost: maxConns,
+		Sitemaps:        o.Sitemaps,
+		SitemapOnly:     o.SitemapOnly,
+		MaxRetries:      retries,
+		CustomHeaders:   headers,
+
+		ConnectTimeout:     time.Duration(o.ConnectTimeoutMs) * time.Millisecond,
+		ReadTimeout:        time.Duration(o.ReadTimeoutMs) * time.Millisecond,
+		TotalTimeout:       time.Duration(o.TimeoutMs) * time.Millisecond,
+		ProxyURL:           o.ProxyURL,
+		CABundle:           o.CABundle,
+		InsecureSkipVerify: o.InsecureSkipVerify,
+		MaxRedirects:       redirects,
+
+		MaxBodyBytes:        o.MaxBodyBytes,
+		AllowedContentTypes: o.AllowedContentTypes,
+		HeadRequests:        o.HeadRequests,
+
+		Normalize:   o.Normalize,
+		Scope:       o.Scope,
+		Incremental: o.Incremental,
+		ContentMode: o.ContentMode,
+		Extraction:  o.Extract,
+
+		CheckExternalLinks: o.CheckExternalLinks,
+	}
+}
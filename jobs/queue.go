@@ -0,0 +1,215 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+	"worker/database"
+	"worker/worker"
+)
+
+const (
+	defaultJobSlots   = 4                // Jobs run at once when MAX_CONCURRENT_JOBS is not set
+	heartbeatInterval = 10 * time.Second // How often leases are renewed and the queue is polled
+)
+
+// jobQueue runs jobs leased from the shared jobs table in a fixed number of execution slots.
+// Every node claims queued jobs highest priority first, then oldest, so any number of processes can share the work.
+type jobQueue struct {
+	mu      sync.Mutex
+	node    string
+	slots   int
+	running int
+	pending map[uint64]*queuedJob // Jobs pinned to this node by a caller following their progress
+}
+
+// queuedJob is a job's worker and a channel closed when it finishes or is canceled
+type queuedJob struct {
+	ctx    context.Context // Canceling it cancels the job
+	worker *worker.Worker
+	done   chan struct{}
+}
+
+var queue = &jobQueue{pending: make(map[uint64]*queuedJob)}
+
+// StartQueue joins this process to the shared job queue. It takes back the jobs a previous run of this
+// node left unfinished, then keeps claiming queued jobs and renewing its leases in the background.
+func StartQueue() {
+	node := NodeID()
+	log.Printf("🖥️ Joining the job queue as node %s", node)
+
+	// Jobs leased by our previous run would otherwise wait for their leases to expire
+	if err := database.ReleaseLeases(node); err != nil {
+		log.Printf("❌ Failed to release leases of node %s: %v", node, err)
+	}
+	queue.dispatch()
+
+	go func() {
+		ticker := time.NewTicker(heartbeatInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			queue.heartbeat()
+			queue.dispatch()
+		}
+	}()
+}
+
+// NodeID identifies this process in job leases, from NODE_ID or the host name
+func NodeID() string {
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+	return queue.nodeID()
+}
+
+// nodeID resolves the node name once; the caller holds q.mu
+func (q *jobQueue) nodeID() string {
+	if q.node == "" {
+		q.node = os.Getenv("NODE_ID")
+	}
+	if q.node == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			hostname = "node-" + strconv.Itoa(os.Getpid())
+		}
+		q.node = hostname
+	}
+	return q.node
+}
+
+// pin creates a job that only this node runs and registers its worker, returning a channel closed when it finishes
+func (q *jobQueue) pin(ctx context.Context, url string, options CrawlOptions, cb worker.WorkerStatusCallback) (uint64, <-chan struct{}, error) {
+	q.mu.Lock()
+	// Registered under the lock so dispatch never claims the job without its worker
+	job, err := database.CreateJob(url, options.JobPriority(), options, q.nodeID())
+	if err != nil {
+		q.mu.Unlock()
+		return 0, nil, err
+	}
+	queued := &queuedJob{ctx: ctx, worker: worker.NewWorker(job.ID, url, options.WorkerConfig(), cb), done: make(chan struct{})}
+	q.pending[job.ID] = queued
+	q.mu.Unlock()
+
+	q.dispatch()
+	return job.ID, queued.done, nil
+}
+
+// Dequeue forgets a pinned job that has not started yet, reporting whether it was waiting here
+func Dequeue(jobID uint64) bool {
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+
+	queued, exists := queue.pending[jobID]
+	if exists {
+		delete(queue.pending, jobID)
+		close(queued.done)
+	}
+	return exists
+}
+
+// dispatch claims runnable jobs while slots are free
+func (q *jobQueue) dispatch() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.slots == 0 {
+		q.slots = jobSlots()
+	}
+
+	for q.running < q.slots {
+		job, err := database.ClaimJob(q.nodeID())
+		if err != nil {
+			log.Printf("❌ Failed to claim a job: %v", err)
+			return
+		}
+		if job == nil {
+			return
+		}
+
+		queued, exists := q.pending[job.ID]
+		delete(q.pending, job.ID)
+		if !exists {
+			// Submitted elsewhere, or interrupted: rebuild the worker from the stored options
+			w, ok := restoreWorker(job)
+			if !ok {
+				continue
+			}
+			queued = &queuedJob{ctx: context.Background(), worker: w, done: make(chan struct{})}
+		}
+
+		// Interrupted and paused jobs continue from their persisted frontier
+		if err := queued.worker.Resume(); err != nil {
+			log.Printf("❌ Failed to restore frontier for job %d: %v", job.ID, err)
+			database.UpdateJobStatus(job.ID, "failed")
+			continue
+		}
+
+		log.Printf("📥 Node %s claimed job %d (priority %d)", q.node, job.ID, job.Priority)
+		q.start(job.ID, queued)
+	}
+}
+
+// start runs a job in a slot; the caller holds q.mu
+func (q *jobQueue) start(jobID uint64, queued *queuedJob) {
+	q.running++
+	StoreJob(jobID, queued.worker)
+
+	go func() {
+		queued.worker.Start(queued.ctx)
+		RemoveJob(jobID, queued.worker)
+		close(queued.done)
+
+		q.mu.Lock()
+		q.running--
+		q.mu.Unlock()
+		q.dispatch()
+	}()
+}
+
+// heartbeat renews this node's leases and stops the jobs it no longer owns,
+// because they were deleted, paused elsewhere, or reassigned after missed heartbeats
+func (q *jobQueue) heartbeat() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	ids, err := database.RenewLeases(q.nodeID())
+	if err != nil {
+		log.Printf("❌ Failed to renew leases of node %s: %v", q.node, err)
+		return
+	}
+	owned := make(map[uint64]bool, len(ids))
+	for _, id := range ids {
+		owned[id] = true
+	}
+
+	activeWorkers.Range(func(key, value interface{}) bool {
+		jobID, w := key.(uint64), value.(*worker.Worker)
+		// Jobs paused here are already finishing their in-flight fetches
+		if !owned[jobID] && !w.Paused() {
+			log.Printf("🛑 Node %s lost the lease on job %d, stopping it", q.node, jobID)
+			w.Abandon()
+			RemoveJob(jobID, w)
+		}
+		return true
+	})
+
+	for jobID, queued := range q.pending {
+		if !owned[jobID] {
+			delete(q.pending, jobID)
+			close(queued.done)
+		}
+	}
+}
+
+// jobSlots reads the number of execution slots from MAX_CONCURRENT_JOBS
+func jobSlots() int {
+	if value := os.Getenv("MAX_CONCURRENT_JOBS"); value != "" {
+		if slots, err := strconv.Atoi(value); err == nil && slots > 0 {
+			return slots
+		}
+		log.Printf("⚠️ Invalid MAX_CONCURRENT_JOBS %q, using %d", value, defaultJobSlots)
+	}
+	return defaultJobSlots
+}
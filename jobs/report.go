@@ -0,0 +1,225 @@
+package jobs
+
+import (
+	"encoding/json"
+	"worker/database"
+	"worker/worker"
+)
+
+// DefaultMaxRedirectHops is the longest redirect chain the link report doesn't flag as too long
+const DefaultMaxRedirectHops = 3
+
+// brokenClasses are the failures that make a link target broken, as opposed to skipped
+var brokenClasses = map[string]bool{
+	string(worker.ErrorHTTPStatus): true,
+	string(worker.ErrorDNS):        true,
+	string(worker.ErrorTLS):        true,
+	string(worker.ErrorTimeout):    true,
+	string(worker.ErrorNetwork):    true,
+	string(worker.ErrorRedirect):   true,
+}
+
+// LinkReport is the link audit of a job: broken link targets and redirect chains, with the pages linking to them
+type LinkReport struct {
+	JobID     uint64            `json:"job_id"`
+	MaxHops   int               `json:"max_hops"` // Chains with more redirects are flagged too_long
+	Summary   LinkReportSummary `json:"summary"`
+	Broken    []BrokenLink      `json:"broken"`
+	Redirects []RedirectChain   `json:"redirects"`
+}
+
+// LinkReportSummary counts the findings of a link report
+type LinkReportSummary struct {
+	Broken          int `json:"broken"`
+	Redirects       int `json:"redirects"`
+	Loops           int `json:"loops"`
+	TooLong         int `json:"too_long"`
+	ExternalChecked int `json:"external_checked"` // External targets requested by check_external_links
+}
+
+// BrokenLink is a link target that answered 4xx/5xx or couldn't be reached
+type BrokenLink struct {
+	URL        string       `json:"url"`
+	Internal   bool         `json:"internal"`
+	StatusCode int          `json:"status_code,omitempty"`
+	ErrorClass string       `json:"error_class"`
+	Error      string       `json:"error"`
+	Sources    []LinkSource `json:"sources"`
+}
+
+// RedirectChain is the redirects followed for a URL
+type RedirectChain struct {
+	URL         string        `json:"url"`
+	Hops        []RedirectHop `json:"hops"`
+	FinalURL    string        `json:"final_url"`
+	FinalStatus int           `json:"final_status,omitempty"` // Absent if the chain was stopped
+	Loop        bool          `json:"loop"`
+	TooLong     bool          `json:"too_long"`
+	Sources     []LinkSource  `json:"sources"`
+}
+
+// RedirectHop is one redirect response in a chain
+type RedirectHop struct {
+	URL    string `json:"url"`
+	Status int    `json:"status"`
+}
+
+// LinkSource is a page linking to a reported URL
+type LinkSource struct {
+	URL    string `json:"url"`
+	Anchor string `json:"anchor"`
+}
+
+// GetLinkReport audits the links of a job. Internal targets are reported from the crawl's own fetches,
+// external ones only if the job checked them.
+func GetLinkReport(jobID uint64, maxHops int) (*LinkReport, error) {
+	job, err := database.LookupJob(jobID)
+	if err != nil {
+		return nil, err
+	}
+	failures, err := database.GetFailures(jobID)
+	if err != nil {
+		return nil, err
+	}
+	checks, err := database.GetLinkChecks(jobID)
+	if err != nil {
+		return nil, err
+	}
+	redirects, err := database.GetRedirects(jobID)
+	if err != nil {
+		return nil, err
+	}
+	links, err := database.GetLinks(jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Pages linking to each target, once per page and anchor
+	sources := make(map[string][]LinkSource)
+	type reference struct {
+		target string
+		source LinkSource
+	}
+	seen := make(map[reference]bool)
+	for _, link := range links {
+		ref := reference{link.TargetURL, LinkSource{URL: link.SourceURL, Anchor: link.Anchor}}
+		if !seen[ref] {
+			seen[ref] = true
+			sources[ref.target] = append(sources[ref.target], ref.source)
+		}
+	}
+	sourcesOf := func(target string) []LinkSource {
+		if list := sources[target]; list != nil {
+			return list
+		}
+		return []LinkSource{}
+	}
+
+	report := &LinkReport{JobID: job.ID, MaxHops: maxHops, Broken: []BrokenLink{}, Redirects: []RedirectChain{}}
+	for _, failure := range failures {
+		if !brokenClasses[failure.ErrorClass] {
+			continue
+		}
+		report.Broken = append(report.Broken, BrokenLink{
+			URL:        failure.URL,
+			Internal:   true,
+			StatusCode: failure.StatusCode,
+			ErrorClass: failure.ErrorClass,
+			Error:      failure.Error,
+			Sources:    sourcesOf(failure.URL),
+		})
+	}
+	for _, check := range checks {
+		report.Summary.ExternalChecked++
+		if check.ErrorClass == "" {
+			continue
+		}
+		report.Broken = append(report.Broken, BrokenLink{
+			URL:        check.URL,
+			StatusCode: check.StatusCode,
+			ErrorClass: check.ErrorClass,
+			Error:      check.Error,
+			Sources:    sourcesOf(check.URL),
+		})
+	}
+
+	for _, redirect := range redirects {
+		chain := RedirectChain{
+			URL:         redirect.URL,
+			Hops:        []RedirectHop{},
+			FinalURL:    redirect.FinalURL,
+			FinalStatus: redirect.FinalStatus,
+			Loop:        redirect.Loop,
+			Sources:     sourcesOf(redirect.URL),
+		}
+		_ = json.Unmarshal(redirect.Hops, &chain.Hops)
+		chain.TooLong = len(chain.Hops) > maxHops
+		if chain.Loop {
+			report.Summary.Loops++
+		}
+		if chain.TooLong {
+			report.Summary.TooLong++
+		}
+		report.Redirects = append(report.Redirects, chain)
+	}
+
+	report.Summary.Broken = len(report.Broken)
+	report.Summary.Redirects = len(report.Redirects)
+	return report, nil
+}
+
+// Page report pagination
+const (
+	DefaultPageReportLimit = 100
+	MaxPageReportLimit     = 1000
+)
+
+// PageReport lists the link metrics of a job's pages
+type PageReport struct {
+	JobID uint64            `json:"job_id"`
+	Total int64             `json:"total"` // Pages matching the filters
+	Pages []PageReportEntry `json:"pages"`
+}
+
+// PageReportEntry is a page with its link metrics
+type PageReportEntry struct {
+	URL        string  `json:"url"`
+	Title      string  `json:"title"`
+	Depth      int     `json:"depth"` // Hop distance at which the crawl found the page
+	Source     string  `json:"source"`
+	PageRank   float64 `json:"pagerank"`
+	InDegree   int     `json:"in_degree"`
+	OutDegree  int     `json:"out_degree"`
+	ClickDepth *int    `json:"click_depth"`
+	Orphan     bool    `json:"orphan"`
+	NoOutlinks bool    `json:"no_outlinks"`
+}
+
+// GetPageReport lists the pages of a job with the link metrics computed when it finished
+func GetPageReport(jobID uint64, query database.PageMetricsQuery) (*PageReport, error) {
+	job, err := database.LookupJob(jobID)
+	if err != nil {
+		return nil, err
+	}
+	pages, total, err := database.GetPageMetrics(jobID, query)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &PageReport{JobID: job.ID, Total: total, Pages: make([]PageReportEntry, 0, len(pages))}
+	for _, page := range pages {
+		report.Pages = append(report.Pages, PageReportEntry{
+			URL:        page.URL,
+			Title:      page.Title,
+			Depth:      page.Depth,
+			Source:     page.Source,
+			PageRank:   page.PageRank,
+			InDegree:   page.InDegree,
+			OutDegree:  page.OutDegree,
+			ClickDepth: page.ClickDepth,
+			Orphan:     page.Orphan,
+			NoOutlinks: page.NoOutlinks,
+		})
+	}
+	return report, nil
+}
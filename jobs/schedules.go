@@ -0,0 +1,250 @@
+package jobs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+	"worker/database"
+)
+
+const (
+	schedulerInterval = 15 * time.Second      // How often due schedules are checked
+	schedulerLeaseTTL = 3 * schedulerInterval // How long another node waits before taking over from a silent leader
+)
+
+// ErrScheduleNotFound is returned when a schedule doesn't exist
+var ErrScheduleNotFound = errors.New("schedule not found")
+
+// ScheduleRequest is a recurring crawl as accepted by the API
+type ScheduleRequest struct {
+	Name     string       `json:"name"`
+	Cron     string       `json:"cron"`     // Five-field cron expression or @hourly, @daily, @weekly, @monthly, @yearly
+	Timezone string       `json:"timezone"` // IANA zone the expression is evaluated in (default UTC)
+	URL      string       `json:"url"`
+	Enabled  *bool        `json:"enabled"` // Default true
+	Options  CrawlOptions `json:"options"` // Crawl options of every run, as accepted by POST /jobs
+}
+
+// Validate checks a schedule before it is stored
+func (r ScheduleRequest) Validate() error {
+	if _, err := parseCron(r.Cron); err != nil {
+		return err
+	}
+	if _, err := time.LoadLocation(r.timezone()); err != nil {
+		return fmt.Errorf("unknown timezone %q", r.Timezone)
+	}
+	// An expression can parse and still never fire, such as one for 30 February
+	if _, err := nextRun(&database.Schedule{Cron: r.Cron, Timezone: r.timezone()}, time.Now()); err != nil {
+		return err
+	}
+	return r.Options.Validate(r.URL)
+}
+
+// timezone returns the schedule's zone name, defaulting to UTC
+func (r ScheduleRequest) timezone() string {
+	if r.Timezone == "" {
+		return "UTC"
+	}
+	return r.Timezone
+}
+
+// schedule builds the stored schedule, computing its next run time if it is enabled
+func (r ScheduleRequest) schedule() (*database.Schedule, error) {
+	optionsJSON, err := json.Marshal(r.Options)
+	if err != nil {
+		return nil, err
+	}
+
+	schedule := &database.Schedule{
+		Name:     r.Name,
+		Cron:     r.Cron,
+		Timezone: r.timezone(),
+		URL:      r.URL,
+		Options:  optionsJSON,
+		Enabled:  r.Enabled == nil || *r.Enabled,
+	}
+	if schedule.Enabled {
+		next, err := nextRun(schedule, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		schedule.NextRunAt = &next
+	}
+	return schedule, nil
+}
+
+// CreateSchedule validates and stores a new schedule
+func CreateSchedule(request ScheduleRequest) (*database.Schedule, error) {
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+	schedule, err := request.schedule()
+	if err != nil {
+		return nil, err
+	}
+	if err := database.CreateSchedule(schedule); err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+// UpdateSchedule replaces a schedule's definition; its next run is computed from now
+func UpdateSchedule(scheduleID uint64, request ScheduleRequest) (*database.Schedule, error) {
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+	schedule, err := request.schedule()
+	if err != nil {
+		return nil, err
+	}
+	schedule.ID = scheduleID
+	if err := database.UpdateSchedule(schedule); err != nil {
+		return nil, scheduleError(err)
+	}
+	return GetSchedule(scheduleID)
+}
+
+// GetSchedule fetches a schedule
+func GetSchedule(scheduleID uint64) (*database.Schedule, error) {
+	schedule, err := database.GetSchedule(scheduleID)
+	if err != nil {
+		return nil, scheduleError(err)
+	}
+	return schedule, nil
+}
+
+// ListSchedules returns all schedules
+func ListSchedules() ([]database.Schedule, error) {
+	return database.GetSchedules()
+}
+
+// DeleteSchedule stops a schedule from firing; jobs it already queued are kept
+func DeleteSchedule(scheduleID uint64) error {
+	return scheduleError(database.DeleteSchedule(scheduleID))
+}
+
+// ScheduleRuns returns the jobs a schedule queued, newest first
+func ScheduleRuns(scheduleID uint64) ([]JobStatus, error) {
+	if _, err := GetSchedule(scheduleID); err != nil {
+		return nil, err
+	}
+
+	dbJobs, err := database.GetScheduleJobs(scheduleID)
+	if err != nil {
+		return nil, err
+	}
+
+	runs := make([]JobStatus, 0, len(dbJobs))
+	for _, job := range dbJobs {
+		status := dbJobStatus(&job)
+		if _, running := GetJob(job.ID); running {
+			// Running on this node, report live progress
+			if live, err := GetJobStatus(job.ID); err == nil {
+				live.Schedule = job.ScheduleID
+				status = live
+			}
+		}
+		runs = append(runs, *status)
+	}
+	return runs, nil
+}
+
+// scheduleError maps a missing schedule onto ErrScheduleNotFound
+func scheduleError(err error) error {
+	if errors.Is(err, database.ErrNotFound) {
+		return ErrScheduleNotFound
+	}
+	return err
+}
+
+// nextRun returns when a schedule next fires after t
+func nextRun(schedule *database.Schedule, t time.Time) (time.Time, error) {
+	cron, err := parseCron(schedule.Cron)
+	if err != nil {
+		return time.Time{}, err
+	}
+	loc, err := time.LoadLocation(schedule.Timezone)
+	if err != nil {
+		return time.Time{}, err
+	}
+	next := cron.next(t.In(loc))
+	if next.IsZero() {
+		return time.Time{}, fmt.Errorf("cron expression %q never fires", schedule.Cron)
+	}
+	return next, nil
+}
+
+// StartScheduler queues runs of due schedules in the background. Every node runs the loop,
+// but only the one holding the scheduler lease fires schedules, so runs are never doubled.
+func StartScheduler() {
+	go func() {
+		leader := false
+		ticker := time.NewTicker(schedulerInterval)
+		defer ticker.Stop()
+		for {
+			leader = runScheduler(leader)
+			<-ticker.C
+		}
+	}()
+}
+
+// runScheduler renews the scheduler lease and fires due schedules if this node holds it,
+// returning whether it does
+func runScheduler(wasLeader bool) bool {
+	node := NodeID()
+	leader, err := database.AcquireLeadership("scheduler", node, schedulerLeaseTTL)
+	if err != nil {
+		log.Printf("❌ Failed to acquire the scheduler lease: %v", err)
+		return false
+	}
+	if leader != wasLeader {
+		if leader {
+			log.Printf("⏰ Node %s is now running schedules", node)
+		} else {
+			log.Printf("⏰ Node %s handed schedules over to another node", node)
+		}
+	}
+	if !leader {
+		return false
+	}
+
+	now := time.Now()
+	due, err := database.GetDueSchedules(now)
+	if err != nil {
+		log.Printf("❌ Failed to fetch due schedules: %v", err)
+		return true
+	}
+	for i := range due {
+		fireSchedule(&due[i], now)
+	}
+	return true
+}
+
+// fireSchedule queues one run of a due schedule. Runs missed while no node was up are not
+// made up: the schedule fires once and moves on to its next time after now.
+func fireSchedule(schedule *database.Schedule, now time.Time) {
+	var options CrawlOptions
+	if err := json.Unmarshal(schedule.Options, &options); err != nil {
+		log.Printf("⚠️ Schedule %d has invalid options: %v", schedule.ID, err)
+		return
+	}
+	next, err := nextRun(schedule, now)
+	if err != nil {
+		log.Printf("⚠️ Schedule %d cannot be evaluated: %v", schedule.ID, err)
+		return
+	}
+
+	job, err := database.FireSchedule(schedule, next, options.JobPriority())
+	if err != nil {
+		log.Printf("❌ Failed to queue a run of schedule %d: %v", schedule.ID, err)
+		return
+	}
+	if job == nil {
+		return
+	}
+
+	log.Printf("⏰ Schedule %d queued job %d, next run at %s", schedule.ID, job.ID, next.Format(time.RFC3339))
+	queue.dispatch()
+}
@@ -4,9 +4,11 @@ import (
 	"log"
 	"os"
 	"sync"
+	_ "time/tzdata" // Schedule time zones, the runtime image has no zoneinfo
 
 	"worker/database"
 	"worker/handlers"
+	"worker/jobs"
 	"worker/server"
 
 	"github.com/gin-gonic/gin"
@@ -22,6 +24,12 @@ func main() {
 	// Initialize database
 	database.InitDatabase()
 
+	// Claim queued jobs, including ones interrupted by a previous shutdown or crash
+	jobs.StartQueue()
+
+	// Queue runs of recurring schedules as they come due
+	jobs.StartScheduler()
+
 	// Set up Gin router
 	router := gin.Default()
 
@@ -35,9 +43,30 @@ func main() {
 		jobRoutes.GET("", handlers.ListJobsHandler)
 		jobRoutes.GET(":id/status", handlers.JobStatusHandler)
 		jobRoutes.GET(":id/results", handlers.JobResultsHandler)
+		jobRoutes.GET(":id/skipped", handlers.JobSkippedHandler)
+		jobRoutes.GET(":id/changes", handlers.JobChangesHandler)
+		jobRoutes.GET(":id/graph", handlers.LinkGraphHandler)
+		jobRoutes.GET(":id/report/links", handlers.LinkReportHandler)
+		jobRoutes.GET(":id/report/pages", handlers.PageReportHandler)
+		jobRoutes.POST(":id/pause", handlers.PauseJobHandler)
+		jobRoutes.POST(":id/resume", handlers.ResumeJobHandler)
 		jobRoutes.DELETE(":id", handlers.DeleteJobHandler)
 	}
 
+	// Compare stored copies of pages across crawls
+	router.GET("/diff", handlers.PageDiffHandler)
+
+	// Schedule routes
+	scheduleRoutes := router.Group("/schedules")
+	{
+		scheduleRoutes.POST("", handlers.CreateScheduleHandler)
+		scheduleRoutes.GET("", handlers.ListSchedulesHandler)
+		scheduleRoutes.GET(":id", handlers.GetScheduleHandler)
+		scheduleRoutes.PUT(":id", handlers.UpdateScheduleHandler)
+		scheduleRoutes.DELETE(":id", handlers.DeleteScheduleHandler)
+		scheduleRoutes.GET(":id/jobs", handlers.ScheduleRunsHandler)
+	}
+
 	// Run both HTTP and gRPC servers concurrently
 	var wg sync.WaitGroup
 	wg.Add(2)
@@ -0,0 +1,445 @@
+package markdown
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// hardBreak stands in for <br> while inline text is assembled, so whitespace collapsing leaves it alone
+const hardBreak = "\x00"
+
+var (
+	multipleSpaces = regexp.MustCompile(` {2,}`)
+	languageClass  = regexp.MustCompile(`(?:^|\s)(?:language|lang)-([\w+#-]+)`)
+)
+
+// block is one rendered Markdown block
+type block struct {
+	text string
+	list bool // Lists stay attached to the list item text before them
+}
+
+// Render converts an HTML node and its descendants to Markdown: ATX headings, lists, fenced code blocks,
+// GitHub tables, links and images. URLs are written as they appear in the HTML.
+func Render(node *html.Node) string {
+	var texts []string
+	for _, b := range renderBlocks(node) {
+		texts = append(texts, b.text)
+	}
+	return strings.Join(texts, "\n\n")
+}
+
+// renderBlocks renders the children of a node, collecting runs of inline content into paragraphs
+func renderBlocks(node *html.Node) []block {
+	var blocks []block
+	var inline []*html.Node
+	flush := func() {
+		if text := renderInline(inline); text != "" {
+			blocks = append(blocks, block{text: text})
+		}
+		inline = inline[:0]
+	}
+
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type != html.ElementNode || !isBlock(child) {
+			inline = append(inline, child)
+			continue
+		}
+		flush()
+		blocks = append(blocks, renderBlock(child)...)
+	}
+	flush()
+	return blocks
+}
+
+// renderBlock renders a block-level element
+func renderBlock(node *html.Node) []block {
+	switch node.DataAtom {
+	case atom.Script, atom.Style, atom.Noscript, atom.Template, atom.Head:
+		return nil
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		text := singleLine(renderInline(children(node)))
+		if text == "" {
+			return nil
+		}
+		level := int(node.Data[1] - '0')
+		return []block{{text: strings.Repeat("#", level) + " " + text}}
+	case atom.Hr:
+		return []block{{text: "---"}}
+	case atom.Pre:
+		return []block{{text: codeBlock(node)}}
+	case atom.Blockquote:
+		inner := joinBlocks(renderBlocks(node))
+		if inner == "" {
+			return nil
+		}
+		return []block{{text: prefixLines(inner, "> ", ">")}}
+	case atom.Ul, atom.Ol:
+		if text := renderList(node); text != "" {
+			return []block{{text: text, list: true}}
+		}
+		return nil
+	case atom.Table:
+		if text := renderTable(node); text != "" {
+			return []block{{text: text}}
+		}
+		return nil
+	case atom.Dt:
+		if text := singleLine(renderInline(children(node))); text != "" {
+			return []block{{text: "**" + text + "**"}}
+		}
+		return nil
+	}
+	return renderBlocks(node)
+}
+
+// renderList renders the items of a <ul> or <ol>, one per line, nesting lists by indentation
+func renderList(node *html.Node) string {
+	number := 1
+	if start, err := strconv.Atoi(attr(node, "start")); err == nil {
+		number = start
+	}
+
+	var items []string
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type != html.ElementNode {
+			continue
+		}
+		marker := "- "
+		if node.DataAtom == atom.Ol {
+			marker = fmt.Sprintf("%d. ", number)
+			number++
+		}
+
+		var content string
+		if child.DataAtom == atom.Li {
+			content = joinBlocks(renderBlocks(child))
+		} else {
+			content = joinBlocks(renderBlock(child))
+		}
+		if content == "" {
+			items = append(items, strings.TrimSpace(marker)) // Keeps the numbering of the items after it
+			continue
+		}
+		indent := strings.Repeat(" ", len(marker))
+		items = append(items, marker+strings.TrimPrefix(prefixLines(content, indent, ""), indent))
+	}
+	return strings.Join(items, "\n")
+}
+
+// renderTable renders a table as a GitHub table, using the first row as the header
+func renderTable(node *html.Node) string {
+	var rows [][]string
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			switch child.DataAtom {
+			case atom.Thead, atom.Tbody, atom.Tfoot:
+				walk(child)
+			case atom.Tr:
+				var row []string
+				for cell := child.FirstChild; cell != nil; cell = cell.NextSibling {
+					if cell.DataAtom == atom.Td || cell.DataAtom == atom.Th {
+						text := singleLine(renderInline(children(cell)))
+						row = append(row, strings.ReplaceAll(text, "|", `\|`))
+					}
+				}
+				rows = append(rows, row)
+			}
+		}
+	}
+	walk(node)
+
+	columns := 0
+	for _, row := range rows {
+		columns = max(columns, len(row))
+	}
+	if columns == 0 {
+		return ""
+	}
+
+	var out strings.Builder
+	writeRow := func(row []string) {
+		for i := 0; i < columns; i++ {
+			cell := ""
+			if i < len(row) {
+				cell = row[i]
+			}
+			out.WriteString("| " + cell + " ")
+		}
+		out.WriteString("|\n")
+	}
+	writeRow(rows[0])
+	writeRow(strings.Split(strings.Repeat("---,", columns-1)+"---", ","))
+	for _, row := range rows[1:] {
+		writeRow(row)
+	}
+	return strings.TrimSuffix(out.String(), "\n")
+}
+
+// codeBlock renders a <pre> as a fenced code block, taking the language from a language-* class
+func codeBlock(node *html.Node) string {
+	code := strings.TrimRight(strings.TrimPrefix(textContent(node), "\n"), "\n\t ")
+
+	language := ""
+	for _, n := range []*html.Node{node, node.FirstChild} {
+		if n != nil && n.Type == html.ElementNode {
+			if match := languageClass.FindStringSubmatch(attr(n, "class")); match != nil {
+				language = match[1]
+				break
+			}
+		}
+	}
+
+	fence := strings.Repeat("`", max(3, longestRun(code, '`')+1))
+	return fence + language + "\n" + code + "\n" + fence
+}
+
+// renderInline renders a run of inline nodes as paragraph text
+func renderInline(nodes []*html.Node) string {
+	var out strings.Builder
+	for _, node := range nodes {
+		writeInline(&out, node)
+	}
+
+	text := multipleSpaces.ReplaceAllString(out.String(), " ")
+	lines := strings.Split(text, hardBreak)
+	for i := range lines {
+		lines[i] = strings.TrimSpace(lines[i])
+	}
+	return strings.TrimSpace(strings.Join(lines, "  \n"))
+}
+
+// writeInline renders an inline node and its descendants
+func writeInline(out *strings.Builder, node *html.Node) {
+	switch node.Type {
+	case html.TextNode:
+		out.WriteString(escape(collapseSpace(node.Data)))
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+
+	switch node.DataAtom {
+	case atom.Script, atom.Style, atom.Noscript, atom.Template:
+	case atom.Br:
+		out.WriteString(hardBreak)
+	case atom.Img:
+		if src := attr(node, "src"); src != "" {
+			out.WriteString("![" + escape(collapseSpace(attr(node, "alt"))) + "](" + destination(src) + title(node) + ")")
+		}
+	case atom.A:
+		text := inlineChildren(node)
+		href := attr(node, "href")
+		switch {
+		case href == "" || strings.HasPrefix(href, "#"):
+			out.WriteString(text)
+		case strings.TrimSpace(text) == "":
+			out.WriteString(text + "<" + href + ">")
+		default:
+			out.WriteString(wrap(text, "[", "]("+destination(href)+title(node)+")"))
+		}
+	case atom.Strong, atom.B:
+		out.WriteString(wrap(inlineChildren(node), "**", "**"))
+	case atom.Em, atom.I:
+		out.WriteString(wrap(inlineChildren(node), "*", "*"))
+	case atom.Del, atom.S, atom.Strike:
+		out.WriteString(wrap(inlineChildren(node), "~~", "~~"))
+	case atom.Code, atom.Kbd, atom.Samp, atom.Tt:
+		code := collapseSpace(textContent(node))
+		if strings.TrimSpace(code) == "" {
+			out.WriteString(code)
+			break
+		}
+		fence := strings.Repeat("`", longestRun(code, '`')+1)
+		if strings.HasPrefix(code, "`") || strings.HasSuffix(code, "`") {
+			code = " " + code + " "
+		}
+		out.WriteString(fence + code + fence)
+	default:
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			writeInline(out, child)
+		}
+	}
+}
+
+// inlineChildren renders the children of an inline element
+func inlineChildren(node *html.Node) string {
+	var out strings.Builder
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		writeInline(&out, child)
+	}
+	return out.String()
+}
+
+// wrap puts text between delimiters, keeping its surrounding whitespace outside them as Markdown requires
+func wrap(text, open, close string) string {
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" {
+		return text
+	}
+	lead := text[:len(text)-len(strings.TrimLeftFunc(text, unicode.IsSpace))]
+	trail := text[len(strings.TrimRightFunc(text, unicode.IsSpace)):]
+	return lead + open + trimmed + close + trail
+}
+
+// destination formats a link or image URL, in angle brackets if it would otherwise end early
+func destination(url string) string {
+	if strings.ContainsAny(url, " ()<>") {
+		return "<" + strings.NewReplacer("<", "%3C", ">", "%3E").Replace(url) + ">"
+	}
+	return url
+}
+
+// title formats the title attribute of a link or image, if it has one
+func title(node *html.Node) string {
+	if value := collapseSpace(attr(node, "title")); strings.TrimSpace(value) != "" {
+		return ` "` + strings.ReplaceAll(strings.TrimSpace(value), `"`, `\"`) + `"`
+	}
+	return ""
+}
+
+// escape backslash-escapes the characters that would otherwise start Markdown formatting.
+// Underscores inside words are left alone since they can't start emphasis there.
+func escape(text string) string {
+	runes := []rune(text)
+	var out strings.Builder
+	for i, r := range runes {
+		switch r {
+		case '\\', '*', '`', '[', ']':
+			out.WriteRune('\\')
+		case '_':
+			if i == 0 || i == len(runes)-1 || !isWord(runes[i-1]) || !isWord(runes[i+1]) {
+				out.WriteRune('\\')
+			}
+		}
+		out.WriteRune(r)
+	}
+	return out.String()
+}
+
+// isBlock reports whether an element starts a block of its own
+func isBlock(node *html.Node) bool {
+	switch node.DataAtom {
+	case atom.Address, atom.Article, atom.Aside, atom.Blockquote, atom.Details, atom.Dialog, atom.Dd, atom.Div,
+		atom.Dl, atom.Dt, atom.Fieldset, atom.Figcaption, atom.Figure, atom.Footer, atom.Form, atom.H1, atom.H2,
+		atom.H3, atom.H4, atom.H5, atom.H6, atom.Header, atom.Hr, atom.Li, atom.Main, atom.Nav, atom.Ol, atom.P,
+		atom.Pre, atom.Section, atom.Summary, atom.Table, atom.Ul, atom.Script, atom.Style, atom.Noscript,
+		atom.Template, atom.Head, atom.Body, atom.Html:
+		return true
+	}
+	return false
+}
+
+// joinBlocks joins blocks with blank lines, except before a list that continues a list item's text
+func joinBlocks(blocks []block) string {
+	var out strings.Builder
+	for i, b := range blocks {
+		if i > 0 {
+			if b.list && !blocks[i-1].list {
+				out.WriteString("\n")
+			} else {
+				out.WriteString("\n\n")
+			}
+		}
+		out.WriteString(b.text)
+	}
+	return out.String()
+}
+
+// prefixLines prefixes every line of text, using emptyPrefix for blank lines
+func prefixLines(text, prefix, emptyPrefix string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if line == "" {
+			lines[i] = emptyPrefix
+		} else {
+			lines[i] = prefix + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// singleLine joins the lines of inline text for places that must fit on one line
+func singleLine(text string) string {
+	return strings.Join(strings.Fields(strings.ReplaceAll(text, "  \n", " ")), " ")
+}
+
+// collapseSpace replaces runs of whitespace with a single space, as browsers render text
+func collapseSpace(text string) string {
+	var out strings.Builder
+	space := false
+	for _, r := range text {
+		if unicode.IsSpace(r) {
+			if !space {
+				out.WriteByte(' ')
+			}
+			space = true
+			continue
+		}
+		space = false
+		out.WriteRune(r)
+	}
+	return out.String()
+}
+
+// textContent returns the text under a node, with its whitespace as written
+func textContent(node *html.Node) string {
+	if node.Type == html.TextNode {
+		return node.Data
+	}
+	var out strings.Builder
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		if child.DataAtom == atom.Br {
+			out.WriteString("\n")
+			continue
+		}
+		out.WriteString(textContent(child))
+	}
+	return out.String()
+}
+
+// longestRun returns the length of the longest run of r in text
+func longestRun(text string, r rune) int {
+	longest, run := 0, 0
+	for _, c := range text {
+		if c == r {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return longest
+}
+
+// children lists the child nodes of a node
+func children(node *html.Node) []*html.Node {
+	var nodes []*html.Node
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		nodes = append(nodes, child)
+	}
+	return nodes
+}
+
+// isWord reports whether r is a letter or digit
+func isWord(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// attr returns the value of a node's attribute, or "" if it is not set
+func attr(node *html.Node, name string) string {
+	for _, attribute := range node.Attr {
+		if attribute.Key == name {
+			return attribute.Val
+		}
+	}
+	return ""
+}
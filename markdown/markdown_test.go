@@ -0,0 +1,53 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{"paragraphs", "<p>One</p><p>Two\n  lines</p>", "One\n\nTwo lines"},
+		{"headings", "<h1>Title</h1><h3>Sub <em>section</em></h3>", "# Title\n\n### Sub *section*"},
+		{"empty heading", "<h2> </h2><p>Text</p>", "Text"},
+		{"emphasis", "<p><strong>bold</strong>, <em> italic </em> and <del>gone</del></p>", "**bold**, *italic* and ~~gone~~"},
+		{"link", `<p><a href="/docs" title="The &quot;docs&quot;">Docs</a></p>`, `[Docs](/docs "The \"docs\"")`},
+		{"link with spaces", `<a href="/a b">A</a>`, "[A](</a b>)"},
+		{"fragment link", `<a href="#top">Top</a>`, "Top"},
+		{"empty link text", `<a href="https://example.com"></a>`, "<https://example.com>"},
+		{"image", `<img src="/logo.png" alt="Logo">`, "![Logo](/logo.png)"},
+		{"line break", "<p>one<br>two</p>", "one  \ntwo"},
+		{"escaping", "<p>2 * 3 = [six] _x_ snake_case</p>", `2 \* 3 = \[six\] \_x\_ snake_case`},
+		{"inline code", "<p>Run <code>go test</code> or <code>a`b</code></p>", "Run `go test` or ``a`b``"},
+		{"code block", `<pre><code class="language-go">func main() {
+	fmt.Println("hi")
+}
+</code></pre>`, "```go\nfunc main() {\n\tfmt.Println(\"hi\")\n}\n```"},
+		{"code block with fences", "<pre>```\ncode\n```</pre>", "````\n```\ncode\n```\n````"},
+		{"unordered list", "<ul><li>One</li><li>Two</li></ul>", "- One\n- Two"},
+		{"ordered list", `<ol start="3"><li>Three</li><li>Four</li></ol>`, "3. Three\n4. Four"},
+		{"nested list", "<ul><li>One<ul><li>Inner</li></ul></li><li>Two</li></ul>", "- One\n  - Inner\n- Two"},
+		{"blockquote", "<blockquote><p>Quote</p><p>More</p></blockquote>", "> Quote\n>\n> More"},
+		{"table", "<table><tr><th>A</th><th>B</th></tr><tr><td>1</td><td>x|y</td></tr><tr><td>2</td></tr></table>",
+			"| A | B |\n| --- | --- |\n| 1 | x\\|y |\n| 2 |  |"},
+		{"definition list", "<dl><dt>Term</dt><dd>Meaning</dd></dl>", "**Term**\n\nMeaning"},
+		{"rule", "<p>Above</p><hr><p>Below</p>", "Above\n\n---\n\nBelow"},
+		{"skipped elements", "<script>alert(1)</script><style>p{}</style><p>Text</p>", "Text"},
+		{"inline and blocks", "<div>Intro <b>text</b><p>Para</p>tail</div>", "Intro **text**\n\nPara\n\ntail"},
+	}
+	for _, test := range tests {
+		doc, err := html.Parse(strings.NewReader(test.html))
+		if err != nil {
+			t.Fatalf("%s: html.Parse: %v", test.name, err)
+		}
+		if got := Render(doc); got != test.want {
+			t.Errorf("%s: Render =\n%q\nwant\n%q", test.name, got, test.want)
+		}
+	}
+}
@@ -2,13 +2,21 @@ package server
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"log"
 	"sync"
-	"worker/database"
 	"worker/jobs"
-	"worker/worker"
 
 	pb "github.com/prorobot-ai/grpc-protos/gen/crawler"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	crawlOptionsHeader  = "crawl-options"
+	defaultGRPCMaxLinks = 16
 )
 
 // CrawlerServer implements the gRPC service
@@ -29,53 +37,65 @@ func NewCrawlerServer() *CrawlerServer {
 func (s *CrawlerServer) StartCrawl(req *pb.CrawlRequest, stream pb.CrawlerService_StartCrawlServer) error {
 	log.Printf("Received Crawl Request for URL: %s", req.Url)
 
-	// Create Job in Database
-	job, err := database.CreateJob(1) // Priority 1 (default)
+	options, err := crawlOptions(stream.Context(), req.Url)
 	if err != nil {
-		log.Printf("❌ Failed to create job: %v", err)
-		return err
+		return status.Error(codes.InvalidArgument, err.Error())
 	}
-	jobID := job.ID
 
-	// Create a cancellable context
-	ctx, cancel := context.WithCancel(context.Background())
+	// The job is canceled when the client disconnects or a progress update can't be delivered
+	ctx, cancel := context.WithCancel(stream.Context())
+
+	// Set up progress callback, held back until the stream is registered
+	registered := make(chan struct{})
+	progressCallback := NewProgressCallback(s, registered, cancel)
 
-	// Store stream for progress updates
+	// Queue a job pinned to this node and store its stream for progress updates
+	jobID, done, err := jobs.HireCrawlerWithProgress(ctx, req.Url, options, progressCallback)
+	if err != nil {
+		cancel()
+		close(registered)
+		log.Printf("❌ Failed to create job: %v", err)
+		return err
+	}
 	s.mu.Lock()
 	s.jobStreams[jobID] = stream
 	s.mu.Unlock()
-
-	// Set up progress callback
-	progressCallback := NewProgressCallback(s, jobID, cancel)
-
-	// Configure and start worker
-	config := worker.WorkerConfig{MaxLinks: 16}
-	newWorker := worker.NewWorker(jobID, req.Url, config, progressCallback)
-
-	jobs.StoreJob(jobID, newWorker)
-
-	// Start worker asynchronously
-	done := make(chan struct{})
-	go func() {
-		newWorker.Start()
-		close(done) // Signal job completion
+	close(registered)
+	defer func() {
+		s.mu.Lock()
+		delete(s.jobStreams, jobID)
+		s.mu.Unlock()
 	}()
 
 	// Keep the gRPC stream open while job runs
 	return s.manageJobLifecycle(jobID, stream, ctx, done)
 }
 
+// crawlOptions reads per-job options from the "crawl-options" metadata header.
+// CrawlRequest only carries the URL, so options travel as the same JSON object POST /jobs accepts.
+func crawlOptions(ctx context.Context, startURL string) (jobs.CrawlOptions, error) {
+	options := jobs.CrawlOptions{MaxLinks: defaultGRPCMaxLinks}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get(crawlOptionsHeader); len(values) > 0 {
+		if err := json.Unmarshal([]byte(values[0]), &options); err != nil {
+			return options, fmt.Errorf("invalid %s metadata: %v", crawlOptionsHeader, err)
+		}
+	}
+
+	return options, options.Validate(startURL)
+}
+
 // manageJobLifecycle keeps the gRPC stream open until the job is done
-func (s *CrawlerServer) manageJobLifecycle(jobID uint64, stream pb.CrawlerService_StartCrawlServer, ctx context.Context, done chan struct{}) error {
+func (s *CrawlerServer) manageJobLifecycle(jobID uint64, stream pb.CrawlerService_StartCrawlServer, ctx context.Context, done <-chan struct{}) error {
 	for {
 		select {
 		case <-ctx.Done():
 			log.Printf("❌ Job %d cancelled due to client disconnection", jobID)
-			jobs.RemoveJob(jobID)
+			jobs.CancelJob(jobID)
 			return nil // End gRPC safely
 		case <-done:
 			log.Printf("✅ Job %d completed successfully", jobID)
-			jobs.RemoveJob(jobID)
 			return nil // Close gRPC stream
 			// case <-time.After(5 * time.Second):
 			// 	err := stream.Send(&pb.CrawlResponse{
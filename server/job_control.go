@@ -0,0 +1,74 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"worker/jobs"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// JobControlServer pauses and resumes jobs over gRPC. The shared crawler protos don't define job control
+// yet, so the service is registered by hand and uses well-known wrapper types: the job ID is a
+// UInt64Value and the reply is a StringValue message.
+type JobControlServer interface {
+	PauseJob(context.Context, *wrapperspb.UInt64Value) (*wrapperspb.StringValue, error)
+	ResumeJob(context.Context, *wrapperspb.UInt64Value) (*wrapperspb.StringValue, error)
+}
+
+// jobControlServiceDesc describes crawler.JobControlService for grpc.Server.RegisterService
+var jobControlServiceDesc = grpc.ServiceDesc{
+	ServiceName: "crawler.JobControlService",
+	HandlerType: (*JobControlServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "PauseJob", Handler: jobControlHandler(JobControlServer.PauseJob)},
+		{MethodName: "ResumeJob", Handler: jobControlHandler(JobControlServer.ResumeJob)},
+	},
+}
+
+// jobControlHandler adapts a JobControlServer method to a unary gRPC handler
+func jobControlHandler(method func(JobControlServer, context.Context, *wrapperspb.UInt64Value) (*wrapperspb.StringValue, error)) grpc.MethodHandler {
+	return func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+		in := new(wrapperspb.UInt64Value)
+		if err := dec(in); err != nil {
+			return nil, err
+		}
+		handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+			return method(srv.(JobControlServer), ctx, req.(*wrapperspb.UInt64Value))
+		}
+		if interceptor == nil {
+			return handler(ctx, in)
+		}
+		return interceptor(ctx, in, &grpc.UnaryServerInfo{Server: srv}, handler)
+	}
+}
+
+// PauseJob pauses a queued or running job, keeping its frontier
+func (s *CrawlerServer) PauseJob(ctx context.Context, req *wrapperspb.UInt64Value) (*wrapperspb.StringValue, error) {
+	if err := jobs.PauseJob(req.GetValue()); err != nil {
+		return nil, jobControlError(err)
+	}
+	return wrapperspb.String("Job paused"), nil
+}
+
+// ResumeJob puts a paused job back in the queue
+func (s *CrawlerServer) ResumeJob(ctx context.Context, req *wrapperspb.UInt64Value) (*wrapperspb.StringValue, error) {
+	if err := jobs.ResumeJob(req.GetValue()); err != nil {
+		return nil, jobControlError(err)
+	}
+	return wrapperspb.String("Job resumed"), nil
+}
+
+// jobControlError maps job errors onto gRPC status codes
+func jobControlError(err error) error {
+	switch {
+	case errors.Is(err, jobs.ErrJobNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, jobs.ErrJobState):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
@@ -11,9 +11,12 @@ import (
 	"google.golang.org/grpc/status"
 )
 
-// NewProgressCallback returns a function to update job progress
-func NewProgressCallback(s *CrawlerServer, jobID uint64, cancel context.CancelFunc) func(uint64, string) {
+// NewProgressCallback returns a function to update job progress. Updates wait until registered is closed,
+// once the job's stream is known.
+func NewProgressCallback(s *CrawlerServer, registered <-chan struct{}, cancel context.CancelFunc) func(uint64, string) {
 	return func(jobID uint64, message string) {
+		<-registered
+
 		s.mu.Lock()
 		defer s.mu.Unlock()
 
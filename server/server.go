@@ -17,7 +17,9 @@ func StartGRPCServer() {
 	}
 
 	server := grpc.NewServer()
-	pb.RegisterCrawlerServiceServer(server, NewCrawlerServer())
+	crawlerServer := NewCrawlerServer()
+	pb.RegisterCrawlerServiceServer(server, crawlerServer)
+	server.RegisterService(&jobControlServiceDesc, crawlerServer)
 
 	reflection.Register(server)
 	log.Println("🚀 gRPC server running on port 50051")
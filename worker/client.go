@@ -0,0 +1,135 @@
+package worker
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	defaultConnectTimeout = 10 * time.Second
+	defaultReadTimeout    = 30 * time.Second
+	defaultTotalTimeout   = 60 * time.Second
+	defaultMaxRedirects   = 10
+)
+
+// errRedirectLoop is wrapped by the error of a redirect chain that revisits a URL
+var errRedirectLoop = errors.New("redirect loop")
+
+// redirectHop is one response in a redirect chain
+type redirectHop struct {
+	URL    string `json:"url"`
+	Status int    `json:"status"`
+}
+
+// NewHTTPClient builds the HTTP client a job uses for every request
+func NewHTTPClient(config WorkerConfig) (*http.Client, error) {
+	connectTimeout := config.ConnectTimeout
+	if connectTimeout <= 0 {
+		connectTimeout = defaultConnectTimeout
+	}
+	readTimeout := config.ReadTimeout
+	if readTimeout <= 0 {
+		readTimeout = defaultReadTimeout
+	}
+	totalTimeout := config.TotalTimeout
+	if totalTimeout <= 0 {
+		totalTimeout = defaultTotalTimeout
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: config.InsecureSkipVerify}
+	if config.CABundle != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM([]byte(config.CABundle)) {
+			return nil, errors.New("CA bundle contains no valid PEM certificates")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	proxy := http.ProxyFromEnvironment
+	if config.ProxyURL != "" {
+		proxyURL, err := url.Parse(config.ProxyURL)
+		if err != nil || proxyURL.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL %q", config.ProxyURL)
+		}
+		proxy = http.ProxyURL(proxyURL)
+	}
+
+	dialer := &net.Dialer{Timeout: connectTimeout, KeepAlive: 30 * time.Second}
+	transport := &http.Transport{
+		Proxy:                 proxy,
+		DialContext:           dialer.DialContext,
+		TLSClientConfig:       tlsConfig,
+		TLSHandshakeTimeout:   connectTimeout,
+		ResponseHeaderTimeout: readTimeout,
+		MaxIdleConnsPerHost:   max(config.MaxConnsPerHost, defaultMaxConnsPerHost),
+		IdleConnTimeout:       90 * time.Second,
+		ForceAttemptHTTP2:     true,
+	}
+
+	maxRedirects := config.MaxRedirects
+	if maxRedirects == 0 {
+		maxRedirects = defaultMaxRedirects
+	} else if maxRedirects < 0 {
+		maxRedirects = 0
+	}
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   totalTimeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if maxRedirects == 0 {
+				// Hand the redirect response back to the caller
+				return http.ErrUseLastResponse
+			}
+			for _, previous := range via {
+				if previous.URL.String() == req.URL.String() {
+					return &FetchError{Class: ErrorRedirect, Err: fmt.Errorf("%w at %s", errRedirectLoop, req.URL)}
+				}
+			}
+			if len(via) > maxRedirects {
+				return &FetchError{Class: ErrorRedirect, Err: fmt.Errorf("stopped after %d redirects", maxRedirects)}
+			}
+			return nil
+		},
+	}, nil
+}
+
+// newRequest builds a request carrying the job's custom headers, canceled along with the job
+func (w *Worker) newRequest(method, urlStr string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(w.ctx, method, urlStr, nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range w.Config.CustomHeaders {
+		req.Header.Set(key, value)
+	}
+	return req, nil
+}
+
+// unfollowedRedirect returns the target of a redirect response the client handed back without following it
+func unfollowedRedirect(resp *http.Response) (string, bool) {
+	switch resp.StatusCode {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		location := resp.Header.Get("Location")
+		return location, location != ""
+	}
+	return "", false
+}
+
+// redirectChain lists the redirect responses that led to resp, oldest first
+func redirectChain(resp *http.Response) []redirectHop {
+	var hops []redirectHop
+	for r := resp.Request.Response; r != nil; r = r.Request.Response {
+		hops = append([]redirectHop{{URL: r.Request.URL.String(), Status: r.StatusCode}}, hops...)
+	}
+	return hops
+}
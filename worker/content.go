@@ -0,0 +1,523 @@
+package worker
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"worker/markdown"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// ContentMode controls what a job stores as a page's text
+type ContentMode string
+
+const (
+	ContentMain ContentMode = "main" // The main content block without boilerplate, keeping paragraph breaks (default)
+	ContentRaw  ContentMode = "raw"  // All text of <body>, as stored before main-content extraction
+)
+
+// Valid reports whether the mode is one of the supported values (empty means main)
+func (m ContentMode) Valid() bool {
+	switch m {
+	case "", ContentMain, ContentRaw:
+		return true
+	}
+	return false
+}
+
+// minMainLength is the text length below which extraction is retried with less aggressive cleaning
+const minMainLength = 250
+
+var (
+	// junkElements never hold readable content
+	junkElements = "script, style, noscript, template, iframe, object, embed, svg, canvas, button, input, select, textarea, " +
+		"nav, aside, dialog, link, meta, [hidden], [aria-hidden=true], [role=navigation], [role=banner], " +
+		"[role=contentinfo], [role=complementary], [role=search], [role=dialog], [role=alertdialog]"
+
+	// blockElements are the children that stop an element from counting as a paragraph when scoring
+	blockElements = "address, article, aside, blockquote, div, dl, figure, footer, form, h1, h2, h3, h4, h5, h6, " +
+		"header, ol, p, pre, section, table, ul"
+
+	// alwaysBoilerplate matches the class or id of overlays that are never content
+	alwaysBoilerplate = regexp.MustCompile(`(?i)cookie|consent|gdpr|popup|modal`)
+
+	// unlikelyCandidate and maybeCandidate match the class or id of blocks removed before scoring,
+	// unless they also look like content
+	unlikelyCandidate = regexp.MustCompile(`(?i)-ad-|ad-break|agegate|banner|breadcrumb|combx|comment|community|disqus|extra|` +
+		`footer|header|legends|menu|newsletter|pager|pagination|related|remark|replies|rss|share|shoutbox|sidebar|` +
+		`skyscraper|social|sponsor|subscribe|supplemental`)
+	maybeCandidate = regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow`)
+
+	// positiveWeight and negativeWeight adjust the score of blocks by their class or id
+	positiveWeight = regexp.MustCompile(`(?i)article|body|content|entry|hentry|h-entry|main|page|post|text|blog|story`)
+	negativeWeight = regexp.MustCompile(`(?i)-ad-|hidden|banner|combx|comment|com-|contact|foot|footnote|masthead|media|meta|` +
+		`outbrain|promo|related|scroll|share|shoutbox|sidebar|skyscraper|sponsor|shopping|tags|tool|widget`)
+
+	// keptAttributes survive in the stripped HTML; everything else, including class, id and style, is dropped
+	keptAttributes = map[string]bool{
+		"href": true, "src": true, "alt": true, "title": true, "colspan": true, "rowspan": true, "headers": true,
+		"scope": true, "datetime": true, "cite": true, "lang": true, "dir": true, "start": true, "reversed": true,
+	}
+)
+
+// pageContent is what a page stores besides its title
+type pageContent struct {
+	Text     string
+	HTML     string // Boilerplate-stripped main content, empty in raw mode
+	Markdown string
+}
+
+// extractContent returns a page's text, its main content as HTML and Markdown, or in raw mode the text
+// and Markdown of the whole body. Links and images are made absolute against base.
+func (w *Worker) extractContent(doc *goquery.Document, base *url.URL) pageContent {
+	if w.Config.ContentMode == ContentRaw {
+		content := pageContent{Text: doc.Find("body").Text()}
+		body := goquery.CloneDocument(doc).Find("body").First()
+		if body.Length() > 0 {
+			body.Find("script, style, noscript, template").Remove()
+			absolutize(body, base)
+			content.Markdown = markdown.Render(body.Nodes[0])
+		}
+		return content
+	}
+
+	content := mainContent(doc, base)
+	contentHTML, _ := content.Html()
+	return pageContent{
+		Text:     contentText(content),
+		HTML:     strings.TrimSpace(contentHTML),
+		Markdown: markdown.Render(content.Nodes[0]),
+	}
+}
+
+// mainContent finds the block holding a page's article in a copy of doc and strips it of boilerplate.
+// The result is a <div> wrapping the block and any sibling blocks that continue it.
+func mainContent(doc *goquery.Document, base *url.URL) *goquery.Selection {
+	var best *goquery.Selection
+	bestLength := -1
+	// Aggressive cleaning can throw away the article on unusual markup, so it is retried without
+	for _, strict := range []bool{true, false} {
+		content := extractMain(goquery.CloneDocument(doc), strict)
+		length := textLength(content)
+		if length > bestLength {
+			best, bestLength = content, length
+		}
+		if length >= minMainLength {
+			break
+		}
+	}
+
+	absolutize(best, base)
+	return best
+}
+
+// extractMain scores the blocks of doc and returns the best one, cleaned
+func extractMain(doc *goquery.Document, strict bool) *goquery.Selection {
+	body := doc.Find("body").First()
+	if body.Length() == 0 {
+		body = doc.Selection
+	}
+
+	removeBoilerplate(body, strict)
+	top, scores := topCandidate(body)
+	content := wrapCandidate(top, scores)
+	cleanContent(content, strict)
+	return content
+}
+
+// removeBoilerplate drops scripts, navigation, hidden elements, overlays and, if strict,
+// blocks whose class or id marks them as page furniture
+func removeBoilerplate(root *goquery.Selection, strict bool) {
+	root.Find(junkElements).Remove()
+	removeComments(root.Nodes[0])
+
+	// Site headers and footers, but not the header or footer of an article
+	root.Find("header, footer").Each(func(_ int, s *goquery.Selection) {
+		if s.ParentsFiltered("article, main, [role=main]").Length() == 0 {
+			s.Remove()
+		}
+	})
+
+	root.Find("*").Each(func(_ int, s *goquery.Selection) {
+		node := s.Nodes[0]
+		if node.Parent == nil || node.DataAtom == atom.Body || node.DataAtom == atom.Html {
+			return // Already removed with an ancestor
+		}
+		style := strings.ReplaceAll(strings.ToLower(attr(node, "style")), " ", "")
+		if strings.Contains(style, "display:none") || strings.Contains(style, "visibility:hidden") {
+			s.Remove()
+			return
+		}
+
+		match := attr(node, "class") + " " + attr(node, "id")
+		if alwaysBoilerplate.MatchString(match) {
+			s.Remove()
+			return
+		}
+		if !strict || node.DataAtom == atom.A || node.DataAtom == atom.Article || node.DataAtom == atom.Main {
+			return
+		}
+		if unlikelyCandidate.MatchString(match) && !maybeCandidate.MatchString(match) &&
+			s.ParentsFiltered("table, code, pre").Length() == 0 {
+			s.Remove()
+		}
+	})
+}
+
+// topCandidate scores each block by the paragraphs inside it, readability style, and returns the best block
+// with the final score of every block
+func topCandidate(root *goquery.Selection) (*html.Node, map[*html.Node]float64) {
+	scores := make(map[*html.Node]float64)
+
+	root.Find("p, pre, td, blockquote, div, section").Each(func(_ int, s *goquery.Selection) {
+		switch s.Nodes[0].DataAtom {
+		case atom.P, atom.Pre:
+		default:
+			// Containers only count as paragraphs if they hold text directly
+			if s.ChildrenFiltered(blockElements).Length() > 0 {
+				return
+			}
+		}
+
+		text := normalizeSpace(s.Text())
+		length := len([]rune(text))
+		if length < 25 {
+			return
+		}
+		score := 1 + float64(strings.Count(text, ",")) + min(float64(length/100), 3)
+
+		// The parent gets the full score, the grandparent half and further ancestors less
+		level := 0
+		for node := s.Nodes[0].Parent; node != nil && node.Type == html.ElementNode && level < 5; node = node.Parent {
+			if _, seen := scores[node]; !seen {
+				scores[node] = initialScore(node)
+			}
+			divider := 1.0
+			switch {
+			case level == 1:
+				divider = 2
+			case level > 1:
+				divider = float64(level * 3)
+			}
+			scores[node] += score / divider
+			if node == root.Nodes[0] {
+				break
+			}
+			level++
+		}
+	})
+
+	// Link-heavy blocks are navigation, however much text they hold
+	var top *html.Node
+	topScore := 0.0
+	root.Find("*").AddSelection(root).Each(func(_ int, s *goquery.Selection) {
+		score, scored := scores[s.Nodes[0]]
+		if !scored {
+			return
+		}
+		score *= 1 - linkDensity(s)
+		scores[s.Nodes[0]] = score
+		if top == nil || score > topScore {
+			top, topScore = s.Nodes[0], score
+		}
+	})
+	if top == nil {
+		return root.Nodes[0], scores
+	}
+
+	return top, scores
+}
+
+// initialScore rates a block by its tag and class before its paragraphs are counted
+func initialScore(node *html.Node) float64 {
+	score := classWeight(node)
+	switch node.DataAtom {
+	case atom.Article, atom.Main:
+		score += 10
+	case atom.Div, atom.Section:
+		score += 5
+	case atom.Pre, atom.Td, atom.Blockquote:
+		score += 3
+	case atom.Address, atom.Ol, atom.Ul, atom.Dl, atom.Dd, atom.Dt, atom.Li, atom.Form:
+		score -= 3
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Th:
+		score -= 5
+	}
+	return score
+}
+
+// classWeight rates a block by whether its class and id suggest content or page furniture
+func classWeight(node *html.Node) float64 {
+	weight := 0.0
+	for _, value := range []string{attr(node, "class"), attr(node, "id")} {
+		if value == "" {
+			continue
+		}
+		if negativeWeight.MatchString(value) {
+			weight -= 25
+		}
+		if positiveWeight.MatchString(value) {
+			weight += 25
+		}
+	}
+	return weight
+}
+
+// wrapCandidate moves the top block into a new <div>, along with the siblings that score well
+// or read like paragraphs of the same article
+func wrapCandidate(top *html.Node, scores map[*html.Node]float64) *goquery.Selection {
+	wrapper := &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div}
+
+	siblings := []*html.Node{top}
+	if top.Parent != nil && top.DataAtom != atom.Body {
+		siblings = siblings[:0]
+		threshold := max(10, scores[top]*0.2)
+		for node := top.Parent.FirstChild; node != nil; node = node.NextSibling {
+			if node == top || (node.Type == html.ElementNode && continuesArticle(node, scores, threshold)) {
+				siblings = append(siblings, node)
+			}
+		}
+	}
+
+	for _, node := range siblings {
+		if node.DataAtom == atom.Body {
+			// Without a better block the whole body is the content
+			for child := node.FirstChild; child != nil; child = node.FirstChild {
+				node.RemoveChild(child)
+				wrapper.AppendChild(child)
+			}
+			continue
+		}
+		node.Parent.RemoveChild(node)
+		wrapper.AppendChild(node)
+	}
+	return goquery.NewDocumentFromNode(wrapper).Selection
+}
+
+// continuesArticle reports whether a sibling of the top block belongs with it
+func continuesArticle(node *html.Node, scores map[*html.Node]float64, threshold float64) bool {
+	if score, scored := scores[node]; scored && score >= threshold {
+		return true
+	}
+	if node.DataAtom != atom.P {
+		return false
+	}
+
+	s := goquery.NewDocumentFromNode(node).Selection
+	text := normalizeSpace(s.Text())
+	length := len([]rune(text))
+	density := linkDensity(s)
+	switch {
+	case length > 80:
+		return density < 0.25
+	case length > 0:
+		return density == 0 && (strings.HasSuffix(text, ".") || strings.Contains(text, ". "))
+	}
+	return false
+}
+
+// cleanContent removes what is left of the page furniture inside the main content and strips
+// presentational attributes
+func cleanContent(content *goquery.Selection, strict bool) {
+	if strict {
+		// Innermost blocks first, so a block is judged without its junk children
+		blocks := content.Find("div, section, ul, ol, table, form, header, footer")
+		for i := blocks.Length() - 1; i >= 0; i-- {
+			block := blocks.Eq(i)
+			if block.ParentsFiltered("pre, code").Length() > 0 {
+				continue
+			}
+			length := textLength(block)
+			media := block.Find("img, picture, video, audio, table, pre").Length()
+			switch {
+			case classWeight(block.Nodes[0]) < 0:
+				block.Remove()
+			case length == 0 && media == 0:
+				block.Remove()
+			case block.Nodes[0].DataAtom != atom.Table && linkDensity(block) > 0.5 && length < 250:
+				block.Remove()
+			}
+		}
+	}
+
+	content.Find("*").Each(func(_ int, s *goquery.Selection) {
+		node := s.Nodes[0]
+		kept := node.Attr[:0]
+		for _, attribute := range node.Attr {
+			// Classes only survive where they name the language of a code block
+			codeLanguage := attribute.Key == "class" && (node.DataAtom == atom.Pre || node.DataAtom == atom.Code) &&
+				strings.Contains(attribute.Val, "lang")
+			if (keptAttributes[attribute.Key] || codeLanguage) && attribute.Namespace == "" {
+				kept = append(kept, attribute)
+			}
+		}
+		node.Attr = kept
+	})
+}
+
+// absolutize resolves the links and image sources of the content against base, dropping javascript: links
+func absolutize(content *goquery.Selection, base *url.URL) {
+	if base == nil {
+		return
+	}
+	for _, name := range []string{"href", "src"} {
+		content.Find("[" + name + "]").Each(func(_ int, s *goquery.Selection) {
+			value, _ := s.Attr(name)
+			if strings.HasPrefix(strings.ToLower(strings.TrimSpace(value)), "javascript:") {
+				s.RemoveAttr(name)
+				return
+			}
+			if resolved, err := base.Parse(strings.TrimSpace(value)); err == nil {
+				s.SetAttr(name, resolved.String())
+			}
+		})
+	}
+}
+
+// contentText renders the text of the content with a blank line between paragraphs,
+// a line break between list items, rows and line breaks, and preformatted text kept as is
+func contentText(content *goquery.Selection) string {
+	var t textWriter
+	for _, node := range content.Nodes {
+		t.node(node, false)
+	}
+	return t.String()
+}
+
+// textWriter collapses whitespace in running text while honoring block boundaries
+type textWriter struct {
+	out    strings.Builder
+	breaks int  // Newlines owed before the next text
+	space  bool // A space is owed before the next text
+}
+
+// node writes the text of a node and its descendants
+func (t *textWriter) node(node *html.Node, pre bool) {
+	switch node.Type {
+	case html.TextNode:
+		t.text(node.Data, pre)
+		return
+	case html.ElementNode, html.DocumentNode:
+	default:
+		return
+	}
+
+	breaks := 0
+	switch node.DataAtom {
+	case atom.P, atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Pre, atom.Blockquote, atom.Ul, atom.Ol,
+		atom.Dl, atom.Table, atom.Figure, atom.Hr, atom.Section, atom.Article, atom.Header, atom.Footer, atom.Main,
+		atom.Aside, atom.Address, atom.Details, atom.Fieldset:
+		breaks = 2
+	case atom.Div, atom.Li, atom.Dt, atom.Dd, atom.Tr, atom.Caption, atom.Figcaption, atom.Summary:
+		breaks = 1
+	case atom.Br:
+		if t.out.Len() > 0 && t.breaks < 2 {
+			t.breaks++
+		}
+		return
+	case atom.Td, atom.Th:
+		if node.PrevSibling != nil {
+			t.space = t.out.Len() > 0
+		}
+	case atom.Img:
+		return
+	}
+
+	t.lineBreak(breaks)
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		t.node(child, pre || node.DataAtom == atom.Pre)
+	}
+	t.lineBreak(breaks)
+}
+
+// lineBreak owes at least n newlines before the next text
+func (t *textWriter) lineBreak(n int) {
+	if n > t.breaks && t.out.Len() > 0 {
+		t.breaks = n
+	}
+}
+
+// text writes running text, collapsing whitespace unless it is preformatted
+func (t *textWriter) text(text string, pre bool) {
+	if pre {
+		if text = strings.TrimRight(text, "\n"); text != "" {
+			t.flush()
+			t.out.WriteString(text)
+		}
+		return
+	}
+	for _, r := range text {
+		if unicode.IsSpace(r) {
+			t.space = t.out.Len() > 0
+			continue
+		}
+		t.flush()
+		t.out.WriteRune(r)
+	}
+}
+
+// flush writes the newlines or space owed before more text
+func (t *textWriter) flush() {
+	if t.breaks > 0 {
+		t.out.WriteString(strings.Repeat("\n", t.breaks))
+	} else if t.space {
+		t.out.WriteByte(' ')
+	}
+	t.breaks, t.space = 0, false
+}
+
+// String returns the text written so far
+func (t *textWriter) String() string {
+	return t.out.String()
+}
+
+// removeComments drops the HTML comments under a node
+func removeComments(node *html.Node) {
+	for child := node.FirstChild; child != nil; {
+		next := child.NextSibling
+		if child.Type == html.CommentNode {
+			node.RemoveChild(child)
+		} else {
+			removeComments(child)
+		}
+		child = next
+	}
+}
+
+// linkDensity is the share of a block's text inside links
+func linkDensity(s *goquery.Selection) float64 {
+	length := textLength(s)
+	if length == 0 {
+		return 0
+	}
+	linked := 0
+	s.Find("a").Each(func(_ int, a *goquery.Selection) {
+		linked += textLength(a)
+	})
+	return float64(linked) / float64(length)
+}
+
+// textLength counts the characters of a selection's text, with whitespace collapsed
+func textLength(s *goquery.Selection) int {
+	return len([]rune(normalizeSpace(s.Text())))
+}
+
+// normalizeSpace collapses runs of whitespace into single spaces and trims the ends
+func normalizeSpace(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// attr returns the value of a node's attribute, or "" if it is not set
+func attr(node *html.Node, name string) string {
+	for _, attribute := range node.Attr {
+		if attribute.Key == name {
+			return attribute.Val
+		}
+	}
+	return ""
+}
@@ -0,0 +1,91 @@
+package worker
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestExtractContent(t *testing.T) {
+	article := `<p>The harbour was quiet that morning, and the fishing boats had not yet returned from the night at sea.</p>
+		<p>By noon the first crews came in, unloading crates of mackerel, herring and the odd lobster onto the pier.</p>
+		<p>Traders gathered along the quay, haggling over prices while gulls circled overhead, waiting for scraps.</p>`
+
+	tests := []struct {
+		name     string
+		page     string
+		want     string   // The whole text, checked when contains is empty
+		contains []string // Text that must be kept
+		excludes []string // Text that must be dropped
+	}{
+		{
+			name: "boilerplate nav and footer",
+			page: `<html><body>
+				<header><a href="/">Acme News</a></header>
+				<nav><ul><li><a href="/">Home</a></li><li><a href="/world">World</a></li></ul></nav>
+				<div class="content"><h1>Harbour report</h1>` + article + `</div>
+				<div class="sidebar"><p>Subscribe to our newsletter for weekly updates, offers and more.</p></div>
+				<footer><p>Copyright Acme News. All rights reserved, everywhere and forever.</p></footer>
+			</body></html>`,
+			contains: []string{"Harbour report", "The harbour was quiet", "waiting for scraps."},
+			excludes: []string{"Acme News", "Home", "World", "Subscribe", "Copyright"},
+		},
+		{
+			name: "empty body",
+			page: `<html><body></body></html>`,
+			want: "",
+		},
+		{
+			name: "whitespace-only body",
+			page: "<html><body>\n\t  \n  <div> </div>\n</body></html>",
+			want: "",
+		},
+		{
+			// Every paragraph is too short to score, so the whole body is kept
+			name: "no candidate passes the threshold",
+			page: `<html><body><div><span>Opening hours</span></div><p>Mon to Fri.</p><p>Closed on Sundays.</p></body></html>`,
+			want: "Opening hours\n\nMon to Fri.\n\nClosed on Sundays.",
+		},
+		{
+			name: "nested article and main",
+			page: `<html><body>
+				<header><p>Site header with a long enough tagline, for sure.</p></header>
+				<main><article>
+					<header><p>By Jane Doe, harbour correspondent</p></header>` + article + `
+					<footer><p>Filed from the quay</p></footer>
+				</article></main>
+				<div class="related"><p>More stories from the coast, the harbour and the fleet.</p></div>
+			</body></html>`,
+			contains: []string{"By Jane Doe", "The harbour was quiet", "waiting for scraps.", "Filed from the quay"},
+			excludes: []string{"Site header", "More stories"},
+		},
+	}
+	base, _ := url.Parse("https://example.com/news/")
+	for _, test := range tests {
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(test.page))
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		w := NewWorker(1, "https://example.com/", WorkerConfig{}, nil)
+		text := w.extractContent(doc, base).Text
+
+		if test.contains == nil && text != test.want {
+			t.Errorf("%s: text = %q, want %q", test.name, text, test.want)
+		}
+		for _, kept := range test.contains {
+			if !strings.Contains(text, kept) {
+				t.Errorf("%s: text %q is missing %q", test.name, text, kept)
+			}
+		}
+		for _, dropped := range test.excludes {
+			if strings.Contains(text, dropped) {
+				t.Errorf("%s: text %q still holds %q", test.name, text, dropped)
+			}
+		}
+		if n := strings.Count(text, "The harbour was quiet"); n > 1 {
+			t.Errorf("%s: article text appears %d times", test.name, n)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package worker
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"time"
+)
+
+// ErrorClass categorizes why a URL could not be crawled
+type ErrorClass string
+
+const (
+	ErrorDNS           ErrorClass = "dns"            // Host name could not be resolved
+	ErrorTLS           ErrorClass = "tls"            // Handshake or certificate failure
+	ErrorTimeout       ErrorClass = "timeout"        // Connect, read or total timeout
+	ErrorNetwork       ErrorClass = "network"        // Connection refused, reset or closed early
+	ErrorHTTPStatus    ErrorClass = "http_status"    // Server answered with 4xx/5xx
+	ErrorRedirect      ErrorClass = "redirect"       // Redirect loop or too many redirects
+	ErrorParse         ErrorClass = "parse"          // Response could not be parsed
+	ErrorTooLarge      ErrorClass = "too_large"      // Response exceeded the size limit
+	ErrorContentType   ErrorClass = "content_type"   // Response is not an allowed media type
+	ErrorRobotsBlocked ErrorClass = "robots_blocked" // Disallowed by robots.txt
+)
+
+const (
+	defaultRetryBaseDelay = 500 * time.Millisecond
+	defaultRetryMaxDelay  = 30 * time.Second
+)
+
+// FetchError is a classified crawl failure
+type FetchError struct {
+	Class      ErrorClass
+	StatusCode int           // HTTP status for ErrorHTTPStatus
+	RetryAfter time.Duration // Server-requested wait, if any
+	Err        error
+}
+
+// Error implements the error interface
+func (e *FetchError) Error() string {
+	if e.Class == ErrorHTTPStatus {
+		return fmt.Sprintf("%s: %d %s", e.Class, e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("%s: %v", e.Class, e.Err)
+}
+
+// Unwrap exposes the underlying error
+func (e *FetchError) Unwrap() error {
+	return e.Err
+}
+
+// Retryable reports whether the failure is transient and worth another attempt
+func (e *FetchError) Retryable() bool {
+	switch e.Class {
+	case ErrorTimeout, ErrorNetwork:
+		return true
+	case ErrorHTTPStatus:
+		return e.StatusCode >= 500 || e.StatusCode == http.StatusTooManyRequests || e.StatusCode == http.StatusRequestTimeout
+	}
+	return false
+}
+
+// Skipped reports whether the URL was deliberately left uncrawled rather than failing
+func (e *FetchError) Skipped() bool {
+	switch e.Class {
+	case ErrorRobotsBlocked, ErrorTooLarge, ErrorContentType:
+		return true
+	}
+	return false
+}
+
+// statusError builds the FetchError for an unsuccessful HTTP response
+func statusError(resp *http.Response) *FetchError {
+	return &FetchError{
+		Class:      ErrorHTTPStatus,
+		StatusCode: resp.StatusCode,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+}
+
+// classifyError maps a transport error onto an ErrorClass
+func classifyError(err error) *FetchError {
+	var fetchErr *FetchError
+	if errors.As(err, &fetchErr) {
+		return fetchErr
+	}
+
+	var (
+		dnsErr     *net.DNSError
+		netErr     net.Error
+		certErr    *tls.CertificateVerificationError
+		recordErr  tls.RecordHeaderError
+		authErr    x509.UnknownAuthorityError
+		hostErr    x509.HostnameError
+		invalidErr x509.CertificateInvalidError
+		alertErr   tls.AlertError
+		class      ErrorClass
+	)
+
+	switch {
+	case errors.As(err, &dnsErr):
+		class = ErrorDNS
+	case errors.As(err, &certErr), errors.As(err, &recordErr), errors.As(err, &authErr),
+		errors.As(err, &hostErr), errors.As(err, &invalidErr), errors.As(err, &alertErr):
+		class = ErrorTLS
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		class = ErrorTimeout
+	default:
+		class = ErrorNetwork
+	}
+
+	return &FetchError{Class: class, Err: err}
+}
+
+// retryDelay returns the jittered exponential backoff before the given retry
+func (w *Worker) retryDelay(attempt int, fetchErr *FetchError) time.Duration {
+	base := w.Config.RetryBaseDelay
+	if base <= 0 {
+		base = defaultRetryBaseDelay
+	}
+	limit := w.Config.RetryMaxDelay
+	if limit <= 0 {
+		limit = defaultRetryMaxDelay
+	}
+
+	delay := min(base<<min(attempt-1, 20), limit)
+	// Equal jitter: half fixed, half random, so retries from many fetchers spread out
+	delay = delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+
+	if fetchErr.RetryAfter > delay {
+		delay = min(fetchErr.RetryAfter, limit)
+	}
+	return delay
+}
@@ -0,0 +1,293 @@
+package worker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+)
+
+// Extraction transforms, applied to field values in order
+const (
+	TransformTrim   = "trim"   // Collapse whitespace and trim the ends
+	TransformRegex  = "regex"  // Keep the first capture group of Pattern, or the whole match if it has none
+	TransformNumber = "number" // Parse the first number, e.g. "1.299,00 €" or "$1,299.00"
+)
+
+// ExtractionSchema describes a structured record extracted from every page it applies to
+type ExtractionSchema struct {
+	URLs   []string          `json:"urls"`   // Globs of the pages to extract from ("/" matches path and query), empty = all pages
+	Fields []ExtractionField `json:"fields"` // Keys of the record
+}
+
+// ExtractionField is one key of an extracted record
+type ExtractionField struct {
+	Name       string                `json:"name"`
+	Selector   string                `json:"selector"`   // CSS selector, relative to the parent field's match for nested fields
+	Attribute  string                `json:"attribute"`  // Take this attribute instead of the element's text
+	Multiple   bool                  `json:"multiple"`   // Collect every match into a list instead of the first match
+	Transforms []ExtractionTransform `json:"transforms"` // Applied to the value in order
+	Fields     []ExtractionField     `json:"fields"`     // Turn each match into an object with these fields instead of a value
+}
+
+// ExtractionTransform converts an extracted value
+type ExtractionTransform struct {
+	Type    string `json:"type"`    // trim, regex or number
+	Pattern string `json:"pattern"` // Regular expression for regex
+	Decimal string `json:"decimal"` // Decimal separator for number, guessed if empty
+}
+
+// extractor is a compiled ExtractionSchema
+type extractor struct {
+	urls   []*regexp.Regexp
+	globs  []string
+	fields []compiledField
+}
+
+// compiledField is a compiled ExtractionField
+type compiledField struct {
+	ExtractionField
+	selector cascadia.Selector
+	patterns []*regexp.Regexp // By transform index, nil for transforms without a pattern
+	fields   []compiledField
+}
+
+// numberPattern finds a number with optional thousands and decimal separators
+var numberPattern = regexp.MustCompile(`[-+]?\d[\d.,' \x{00a0}\x{202f}]*`)
+
+// newExtractor compiles an extraction schema, returning nil if it has no fields
+func newExtractor(schema ExtractionSchema) (*extractor, error) {
+	if len(schema.Fields) == 0 {
+		if len(schema.URLs) > 0 {
+			return nil, errors.New("extraction schema has urls but no fields")
+		}
+		return nil, nil
+	}
+
+	e := &extractor{globs: schema.URLs}
+	for _, glob := range schema.URLs {
+		pattern, err := regexp.Compile(globToRegexp(glob))
+		if err != nil {
+			return nil, fmt.Errorf("extraction url %q: %v", glob, err)
+		}
+		e.urls = append(e.urls, pattern)
+	}
+
+	fields, err := compileFields(schema.Fields, "")
+	if err != nil {
+		return nil, err
+	}
+	e.fields = fields
+	return e, nil
+}
+
+// ValidateExtraction checks an extraction schema before a job is created
+func ValidateExtraction(schema ExtractionSchema) error {
+	_, err := newExtractor(schema)
+	return err
+}
+
+// compileFields compiles the selectors and transform patterns of a list of fields
+func compileFields(fields []ExtractionField, parent string) ([]compiledField, error) {
+	compiled := make([]compiledField, 0, len(fields))
+	seen := make(map[string]bool)
+	for _, field := range fields {
+		path := parent + field.Name
+		if field.Name == "" {
+			return nil, fmt.Errorf("extraction field %q: name is required", path)
+		}
+		if seen[field.Name] {
+			return nil, fmt.Errorf("extraction field %q: duplicate name", path)
+		}
+		seen[field.Name] = true
+
+		selector, err := cascadia.Compile(field.Selector)
+		if err != nil {
+			return nil, fmt.Errorf("extraction field %q: invalid selector %q: %v", path, field.Selector, err)
+		}
+		c := compiledField{ExtractionField: field, selector: selector}
+
+		if len(field.Fields) > 0 {
+			if field.Attribute != "" || len(field.Transforms) > 0 {
+				return nil, fmt.Errorf("extraction field %q: fields can't be combined with attribute or transforms", path)
+			}
+			if c.fields, err = compileFields(field.Fields, path+"."); err != nil {
+				return nil, err
+			}
+		}
+
+		for i, transform := range field.Transforms {
+			var pattern *regexp.Regexp
+			switch transform.Type {
+			case TransformTrim:
+			case TransformRegex:
+				if transform.Pattern == "" {
+					return nil, fmt.Errorf("extraction field %q: transform %d: regex needs a pattern", path, i+1)
+				}
+				if pattern, err = regexp.Compile(transform.Pattern); err != nil {
+					return nil, fmt.Errorf("extraction field %q: transform %d: %v", path, i+1, err)
+				}
+			case TransformNumber:
+				if transform.Decimal != "" && transform.Decimal != "." && transform.Decimal != "," {
+					return nil, fmt.Errorf("extraction field %q: transform %d: decimal must be . or ,", path, i+1)
+				}
+				if i < len(field.Transforms)-1 {
+					return nil, fmt.Errorf("extraction field %q: transform %d: number must be the last transform", path, i+1)
+				}
+			default:
+				return nil, fmt.Errorf("extraction field %q: transform %d: type must be one of trim, regex or number", path, i+1)
+			}
+			c.patterns = append(c.patterns, pattern)
+		}
+		compiled = append(compiled, c)
+	}
+	return compiled, nil
+}
+
+// appliesTo reports whether a page URL matches the schema's URL globs
+func (e *extractor) appliesTo(u *url.URL) bool {
+	if len(e.urls) == 0 {
+		return true
+	}
+	for i, pattern := range e.urls {
+		subject := u.String()
+		if strings.HasPrefix(e.globs[i], "/") {
+			subject = u.RequestURI()
+		}
+		if pattern.MatchString(subject) {
+			return true
+		}
+	}
+	return false
+}
+
+// extract returns the record of a page as JSON, or nil if the job has no schema or it doesn't apply to the page
+func (w *Worker) extract(doc *goquery.Document, pageURL string) []byte {
+	if w.extractor == nil {
+		return nil
+	}
+	parsedURL, err := url.Parse(pageURL)
+	if err != nil || !w.extractor.appliesTo(parsedURL) {
+		return nil
+	}
+
+	record, err := json.Marshal(extractRecord(doc.Selection, w.extractor.fields))
+	if err != nil {
+		return nil
+	}
+	return record
+}
+
+// extractRecord extracts the fields within a selection. Missing single values are null, missing lists empty.
+func extractRecord(s *goquery.Selection, fields []compiledField) map[string]interface{} {
+	record := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		matches := s.FindMatcher(field.selector)
+		if !field.Multiple {
+			record[field.Name] = nil
+			if matches.Length() > 0 {
+				if value, ok := field.value(matches.First()); ok {
+					record[field.Name] = value
+				}
+			}
+			continue
+		}
+
+		values := []interface{}{}
+		matches.Each(func(_ int, match *goquery.Selection) {
+			if value, ok := field.value(match); ok {
+				values = append(values, value)
+			}
+		})
+		record[field.Name] = values
+	}
+	return record
+}
+
+// value extracts a field from one match, returning false if a transform rejected it
+func (f compiledField) value(match *goquery.Selection) (interface{}, bool) {
+	if len(f.fields) > 0 {
+		return extractRecord(match, f.fields), true
+	}
+
+	var value string
+	if f.Attribute != "" {
+		attribute, exists := match.Attr(f.Attribute)
+		if !exists {
+			return nil, false
+		}
+		value = attribute
+	} else {
+		value = match.Text()
+	}
+
+	for i, transform := range f.Transforms {
+		switch transform.Type {
+		case TransformTrim:
+			value = normalizeSpace(value)
+		case TransformRegex:
+			groups := f.patterns[i].FindStringSubmatch(value)
+			if groups == nil {
+				return nil, false
+			}
+			value = groups[0]
+			if len(groups) > 1 {
+				value = groups[1]
+			}
+		case TransformNumber:
+			return parseNumber(value, transform.Decimal)
+		}
+	}
+	return value, true
+}
+
+// parseNumber parses the first number in text. Without a decimal separator it takes the last of "." and ","
+// as the decimal point if both occur, and a lone separator as thousands if it repeats or, for ",", precedes
+// exactly three digits.
+func parseNumber(text, decimal string) (interface{}, bool) {
+	number := numberPattern.FindString(text)
+	number = strings.TrimRight(strings.NewReplacer(" ", "", "'", "", "\u00a0", "", "\u202f", "").Replace(number), ".,")
+	if number == "" {
+		return nil, false
+	}
+
+	if decimal == "" {
+		lastDot, lastComma := strings.LastIndex(number, "."), strings.LastIndex(number, ",")
+		switch {
+		case lastDot >= 0 && lastComma >= 0:
+			decimal = "."
+			if lastComma > lastDot {
+				decimal = ","
+			}
+		case lastComma >= 0:
+			decimal = ","
+			if strings.Count(number, ",") > 1 || len(number)-lastComma-1 == 3 {
+				decimal = "."
+			}
+		default:
+			decimal = "."
+			if strings.Count(number, ".") > 1 {
+				decimal = ","
+			}
+		}
+	}
+
+	thousands := ","
+	if decimal == "," {
+		thousands = "."
+	}
+	number = strings.ReplaceAll(number, thousands, "")
+	number = strings.Replace(number, decimal, ".", 1)
+
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return nil, false
+	}
+	return value, true
+}
@@ -0,0 +1,41 @@
+package worker
+
+import "testing"
+
+func TestParseNumber(t *testing.T) {
+	tests := []struct {
+		text    string
+		decimal string
+		want    float64
+		ok      bool
+	}{
+		{"42", "", 42, true},
+		{"Price: $1,299.99", "", 1299.99, true},
+		{"1.299,99 €", "", 1299.99, true},
+		{"3,5 kg", "", 3.5, true},
+		{"1,000", "", 1000, true},
+		{"1,000,000", "", 1000000, true},
+		{"1.000.000", "", 1000000, true},
+		{"2.5", "", 2.5, true},
+		{"1 234,5", "", 1234.5, true},
+		{"1 234", "", 1234, true},
+		{"CHF 1'234.50", "", 1234.5, true},
+		{"-17 °C", "", -17, true},
+		{"+3", "", 3, true},
+		{"Total: 12.", "", 12, true},
+		{"1,000", ",", 1, true},
+		{"1.234", ",", 1234, true},
+		{"no digits", "", 0, false},
+		{"", "", 0, false},
+	}
+	for _, test := range tests {
+		value, ok := parseNumber(test.text, test.decimal)
+		if ok != test.ok {
+			t.Errorf("parseNumber(%q, %q) ok = %v, want %v", test.text, test.decimal, ok, test.ok)
+			continue
+		}
+		if ok && value != test.want {
+			t.Errorf("parseNumber(%q, %q) = %v, want %v", test.text, test.decimal, value, test.want)
+		}
+	}
+}
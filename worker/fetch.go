@@ -0,0 +1,221 @@
+package worker
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"worker/database"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+const (
+	defaultMaxBodyBytes = 10 * 1024 * 1024
+	sniffLen            = 512 // Bytes http.DetectContentType considers
+)
+
+// defaultContentTypes are the media types crawled when a job sets no allowlist
+var defaultContentTypes = []string{"text/html", "application/xhtml+xml"}
+
+// fetchPage requests u, retrying transient failures with jittered exponential backoff. Attempts are
+// recorded under urlStr, the normalized URL. A 304 Not Modified answer to a conditional request is
+// returned with a nil document.
+func (w *Worker) fetchPage(urlStr string, u *url.URL) (*goquery.Document, *http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		doc, resp, err := w.fetchOnce(urlStr, u)
+		if err == nil {
+			return doc, resp, nil
+		}
+		if w.ctx.Err() != nil {
+			return nil, nil, w.ctx.Err() // Canceled, not a failure of the URL
+		}
+
+		fetchErr := classifyError(err)
+		final := attempt > w.Config.MaxRetries || !fetchErr.Retryable()
+		w.recordAttempt(urlStr, attempt, fetchErr, final)
+		if final {
+			return nil, nil, fetchErr
+		}
+
+		delay := w.retryDelay(attempt, fetchErr)
+		log.Printf("🔁 Retrying %s in %s after %v", u, delay, fetchErr)
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-w.ctx.Done():
+			timer.Stop()
+			return nil, nil, w.ctx.Err()
+		}
+	}
+}
+
+// fetchOnce makes a single request for u and parses the HTML response
+func (w *Worker) fetchOnce(urlStr string, u *url.URL) (*goquery.Document, *http.Response, error) {
+	if w.Config.HeadRequests {
+		if err := w.preflight(u); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	req, err := w.newRequest("GET", u.String())
+	if err != nil {
+		return nil, nil, err
+	}
+	w.setConditional(req, urlStr)
+	req, timings := traceTimings(req)
+
+	// Wait for the host's politeness slot
+	release, err := politeness.acquire(w.ctx, u.Host, w.hostDelay(u), w.Config.MaxConnsPerHost)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resp, err := w.client.Do(req)
+	w.recordRedirects(urlStr, resp, err)
+	if err != nil {
+		release(nil)
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		release(resp)
+		return nil, resp, statusError(resp)
+	}
+	if _, unfollowed := unfollowedRedirect(resp); unfollowed || resp.StatusCode == http.StatusNotModified {
+		// A redirect that isn't followed, or unchanged since the previous crawl: there is no body to parse
+		release(resp)
+		return nil, resp, nil
+	}
+
+	body, err := w.readBody(resp)
+	release(resp)
+	if err != nil {
+		return nil, resp, err
+	}
+	timings.finish()
+
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, resp, &FetchError{Class: ErrorParse, Err: err}
+	}
+	return doc, resp, nil
+}
+
+// preflight sends a HEAD request and rejects resources that are too large or not HTML before downloading them.
+// Servers that don't support HEAD are let through to the GET checks.
+func (w *Worker) preflight(u *url.URL) error {
+	req, err := w.newRequest("HEAD", u.String())
+	if err != nil {
+		return err
+	}
+
+	release, err := politeness.acquire(w.ctx, u.Host, w.hostDelay(u), w.Config.MaxConnsPerHost)
+	if err != nil {
+		return err
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		release(nil)
+		return err
+	}
+	resp.Body.Close()
+	release(resp)
+
+	if _, unfollowed := unfollowedRedirect(resp); unfollowed || resp.StatusCode >= 400 {
+		return nil
+	}
+	if limit := w.maxBodyBytes(); resp.ContentLength > limit {
+		return &FetchError{Class: ErrorTooLarge, Err: fmt.Errorf("Content-Length %d exceeds %d bytes", resp.ContentLength, limit)}
+	}
+	if declared := resp.Header.Get("Content-Type"); declared != "" {
+		if mediaType, _, err := mime.ParseMediaType(declared); err == nil && !w.contentTypeAllowed(mediaType) {
+			return &FetchError{Class: ErrorContentType, Err: fmt.Errorf("content type %s is not allowed", mediaType)}
+		}
+	}
+	return nil
+}
+
+// readBody reads a GET response, aborting as soon as it turns out to be too large or of a disallowed type
+func (w *Worker) readBody(resp *http.Response) ([]byte, error) {
+	limit := w.maxBodyBytes()
+	if resp.ContentLength > limit {
+		return nil, &FetchError{Class: ErrorTooLarge, Err: fmt.Errorf("Content-Length %d exceeds %d bytes", resp.ContentLength, limit)}
+	}
+
+	// Sniff the first bytes instead of trusting the Content-Type header alone
+	reader := bufio.NewReaderSize(io.LimitReader(resp.Body, limit+1), sniffLen)
+	head, err := reader.Peek(sniffLen)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, err
+	}
+	if mediaType := sniffContentType(resp.Header.Get("Content-Type"), head); !w.contentTypeAllowed(mediaType) {
+		return nil, &FetchError{Class: ErrorContentType, Err: fmt.Errorf("content type %s is not allowed", mediaType)}
+	}
+
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > limit {
+		return nil, &FetchError{Class: ErrorTooLarge, Err: fmt.Errorf("body exceeds %d bytes", limit)}
+	}
+	return body, nil
+}
+
+// sniffContentType picks the media type of a response from its sniffed bytes and declared header.
+// The sniffer can't tell markup from plain text reliably, so the header wins for generic text types.
+func sniffContentType(declared string, head []byte) string {
+	sniffed, _, _ := mime.ParseMediaType(http.DetectContentType(head))
+	declaredType, _, err := mime.ParseMediaType(declared)
+	if err == nil && (sniffed == "text/plain" || sniffed == "text/xml") {
+		return declaredType
+	}
+	return sniffed
+}
+
+// contentTypeAllowed checks a media type against the job's allowlist
+func (w *Worker) contentTypeAllowed(mediaType string) bool {
+	allowed := w.Config.AllowedContentTypes
+	if len(allowed) == 0 {
+		allowed = defaultContentTypes
+	}
+	for _, t := range allowed {
+		if strings.EqualFold(t, mediaType) {
+			return true
+		}
+	}
+	return false
+}
+
+// maxBodyBytes returns the job's response size limit
+func (w *Worker) maxBodyBytes() int64 {
+	if w.Config.MaxBodyBytes > 0 {
+		return w.Config.MaxBodyBytes
+	}
+	return defaultMaxBodyBytes
+}
+
+// recordAttempt stores a failed fetch attempt
+func (w *Worker) recordAttempt(urlStr string, attempt int, fetchErr *FetchError, final bool) {
+	err := database.AddFetchAttempt(w.ctx, &database.FetchAttempt{
+		JobID:      w.JobID,
+		URL:        urlStr,
+		Attempt:    attempt,
+		ErrorClass: string(fetchErr.Class),
+		StatusCode: fetchErr.StatusCode,
+		Error:      fetchErr.Error(),
+		Final:      final,
+	})
+	if err != nil && w.ctx.Err() == nil {
+		log.Printf("❌ Failed to record fetch attempt: %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+package worker
+
+import (
+	"container/heap"
+	"time"
+)
+
+// Page sources, recording how a URL entered the frontier
+const (
+	SourceSeed     = "seed"     // The job's start URL
+	SourceLink     = "link"     // Found in an <a href> on a crawled page
+	SourceSitemap  = "sitemap"  // Listed in a sitemap
+	SourcePrevious = "previous" // Stored by the previous crawl of the start URL, revisited by incremental jobs
+)
+
+// Frontier priorities, following the sitemap protocol's 0.0-1.0 scale
+const (
+	seedPriority    = 1.0
+	defaultPriority = 0.5
+)
+
+// frontierItem is a URL waiting to be crawled.
+type frontierItem struct {
+	URL      string    // Normalized URL, the dedup key
+	Fetch    string    // URL as written, requested instead of URL; empty if they are the same
+	Depth    int       // Hop distance from the start URL
+	Priority float64   // Sitemap <priority>, higher first within a level
+	LastMod  time.Time // Sitemap <lastmod>, newer first among equal priorities
+	Source   string    // How the URL was discovered
+	seq      uint64    // Insertion order, used to keep the queue FIFO within a level
+}
+
+// fetchURL returns the URL to request for an item
+func (item frontierItem) fetchURL() string {
+	if item.Fetch != "" {
+		return item.Fetch
+	}
+	return item.URL
+}
+
+// frontier is a breadth-first queue of URLs: shallower items always come out first.
+type frontier struct {
+	items []frontierItem
+	seq   uint64
+}
+
+// push adds a URL to the frontier
+func (f *frontier) push(item frontierItem) {
+	item.seq = f.seq
+	f.seq++
+	heap.Push((*frontierHeap)(f), item)
+}
+
+// pop removes the next URL to crawl
+func (f *frontier) pop() (frontierItem, bool) {
+	if len(f.items) == 0 {
+		return frontierItem{}, false
+	}
+	return heap.Pop((*frontierHeap)(f)).(frontierItem), true
+}
+
+// Len returns the number of queued URLs
+func (f *frontier) Len() int {
+	return len(f.items)
+}
+
+// frontierHeap implements heap.Interface ordered by depth, priority, freshness, then insertion order.
+type frontierHeap frontier
+
+func (h *frontierHeap) Len() int { return len(h.items) }
+
+func (h *frontierHeap) Less(i, j int) bool {
+	a, b := h.items[i], h.items[j]
+	if a.Depth != b.Depth {
+		return a.Depth < b.Depth
+	}
+	if a.Priority != b.Priority {
+		return a.Priority > b.Priority
+	}
+	if !a.LastMod.Equal(b.LastMod) {
+		return a.LastMod.After(b.LastMod)
+	}
+	return a.seq < b.seq
+}
+
+func (h *frontierHeap) Swap(i, j int) { h.items[i], h.items[j] = h.items[j], h.items[i] }
+
+func (h *frontierHeap) Push(x interface{}) { h.items = append(h.items, x.(frontierItem)) }
+
+func (h *frontierHeap) Pop() interface{} {
+	n := len(h.items)
+	item := h.items[n-1]
+	h.items = h.items[:n-1]
+	return item
+}
@@ -0,0 +1,282 @@
+package worker
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"worker/database"
+)
+
+// loadPrevious indexes the pages of the previous completed crawl of the same start URL,
+// which new pages are compared against
+func (w *Worker) loadPrevious() {
+	jobID, err := database.PreviousJob(w.JobID, w.StartURL)
+	if err != nil {
+		log.Printf("⚠️ Failed to find the previous crawl of job %d: %v", w.JobID, err)
+		return
+	}
+	if jobID == 0 {
+		return
+	}
+
+	pages, err := database.GetPageValidators(jobID)
+	if err != nil {
+		log.Printf("⚠️ Failed to load the pages of job %d: %v", jobID, err)
+		return
+	}
+
+	w.previousJob = jobID
+	w.previous = make(map[string]database.Page, len(pages))
+	for _, page := range pages {
+		w.previous[page.URL] = page
+	}
+	w.sameExtraction = w.extractedWithSameSchema(jobID)
+	log.Printf("🔁 Job %d compares against job %d (%d pages)", w.JobID, jobID, len(pages))
+}
+
+// extractedWithSameSchema reports whether a previous job extracted records with this job's schema,
+// so the records of pages that are unchanged since then are still valid
+func (w *Worker) extractedWithSameSchema(jobID uint64) bool {
+	optionsJSON, err := database.GetJobOptions(jobID)
+	if err != nil {
+		log.Printf("⚠️ Failed to load the options of job %d: %v", jobID, err)
+		return false
+	}
+	var options struct {
+		Extract ExtractionSchema `json:"extract"`
+	}
+	if len(optionsJSON) > 0 && json.Unmarshal(optionsJSON, &options) != nil {
+		return false
+	}
+	previous, err := json.Marshal(options.Extract)
+	if err != nil {
+		return false
+	}
+	current, err := json.Marshal(w.Config.Extraction)
+	return err == nil && string(previous) == string(current)
+}
+
+// seedPrevious queues the pages of the previous crawl, so pages answering 304 Not Modified
+// don't hide the pages only they link to
+func (w *Worker) seedPrevious() {
+	items := make([]frontierItem, 0, len(w.previous))
+	for _, page := range w.previous {
+		if w.Config.MaxDepth > 0 && page.Depth > w.Config.MaxDepth {
+			continue
+		}
+		items = append(items, frontierItem{URL: page.URL, Fetch: requestURL(page), Depth: page.Depth, Priority: defaultPriority, Source: SourcePrevious})
+	}
+	count := w.enqueue(w.base, items...)
+
+	log.Printf("🔁 Job %d seeded %d URLs from job %d", w.JobID, count, w.previousJob)
+	w.report(fmt.Sprintf("Seeded %d URLs from the previous crawl", count))
+}
+
+// requestURL returns how a previous page's URL was written, if it was requested as something other than its normalized form
+func requestURL(page database.Page) string {
+	var metadata struct {
+		RequestURL string `json:"request_url"`
+	}
+	if len(page.Metadata) > 0 {
+		_ = json.Unmarshal(page.Metadata, &metadata)
+	}
+	return metadata.RequestURL
+}
+
+// setConditional asks the server to answer 304 Not Modified if a page is unchanged since the previous crawl.
+// Pages are fetched in full if the previous crawl used another extraction schema, so their records are rebuilt.
+func (w *Worker) setConditional(req *http.Request, urlStr string) {
+	if !w.Config.Incremental || !w.sameExtraction {
+		return
+	}
+	page, exists := w.previous[urlStr]
+	if !exists {
+		return
+	}
+	if page.ETag != "" {
+		req.Header.Set("If-None-Match", page.ETag)
+	}
+	if page.LastModified != "" {
+		req.Header.Set("If-Modified-Since", page.LastModified)
+	}
+}
+
+// changeStatus compares a page's content hash with the previous crawl
+func (w *Worker) changeStatus(urlStr, hash string) string {
+	page, exists := w.previous[urlStr]
+	switch {
+	case !exists:
+		return database.ChangeNew
+	case page.ContentHash != hash:
+		return database.ChangeChanged
+	}
+	return database.ChangeUnchanged
+}
+
+// storeUnchanged copies the previous crawl's page for a URL the server answered with 304 Not Modified
+func (w *Worker) storeUnchanged(item frontierItem, resp *http.Response) {
+	previous, err := database.GetPage(w.previous[item.URL].ID)
+	if err != nil {
+		log.Printf("❌ Failed to load the previous copy of %s: %v", item.URL, err)
+		return
+	}
+
+	page := &database.Page{
+		JobID:        w.JobID,
+		URL:          item.URL,
+		Title:        previous.Title,
+		Content:      previous.Content,
+		ContentHTML:  previous.ContentHTML,
+		Markdown:     previous.Markdown,
+		Extracted:    previous.Extracted, // Conditional requests are only sent if the previous crawl used the same schema
+		Depth:        item.Depth,
+		Source:       item.Source,
+		ETag:         firstNonEmpty(resp.Header.Get("ETag"), previous.ETag),
+		LastModified: firstNonEmpty(resp.Header.Get("Last-Modified"), previous.LastModified),
+		ContentHash:  previous.ContentHash,
+		ChangeStatus: database.ChangeUnchanged,
+	}
+	// What the page declares about itself is unchanged too; only the fetch is new
+	metadata := map[string]interface{}{}
+	if len(previous.Metadata) > 0 {
+		if err := json.Unmarshal(previous.Metadata, &metadata); err != nil {
+			log.Printf("⚠️ Failed to read the previous metadata of %s: %v", item.URL, err)
+		}
+	}
+	for _, key := range []string{"timings", "redirects", "final_url", "request_url"} {
+		delete(metadata, key)
+	}
+	if item.Fetch != "" {
+		metadata["request_url"] = item.Fetch
+	}
+	metadata["status"] = resp.StatusCode
+	metadata["timestamp"] = time.Now().Format(time.RFC3339)
+	metadata["previous_job_id"] = w.previousJob
+	if hops := redirectChain(resp); len(hops) > 0 {
+		metadata["redirects"] = hops
+		metadata["final_url"] = resp.Request.URL.String()
+	}
+	if timings := responseTimings(resp); timings != nil {
+		metadata["timings"] = timings.metadata()
+	}
+	if !w.storePage(page, metadata) || page.ID == 0 {
+		return
+	}
+	if err := database.CopyLinks(w.ctx, previous.ID, page); err != nil && w.ctx.Err() == nil {
+		log.Printf("⚠️ Failed to copy the links of %s: %v", item.URL, err)
+	}
+}
+
+// storeGone records that a page of the previous crawl now answers 404 or 410
+func (w *Worker) storeGone(item frontierItem, fetchErr *FetchError) {
+	previous, exists := w.previous[item.URL]
+	if !exists || fetchErr.Class != ErrorHTTPStatus ||
+		(fetchErr.StatusCode != http.StatusNotFound && fetchErr.StatusCode != http.StatusGone) {
+		return
+	}
+
+	page := &database.Page{
+		JobID:        w.JobID,
+		URL:          item.URL,
+		Depth:        item.Depth,
+		Source:       item.Source,
+		ContentHash:  previous.ContentHash,
+		ChangeStatus: database.ChangeGone,
+	}
+	metadata := map[string]interface{}{
+		"status":          fetchErr.StatusCode,
+		"timestamp":       time.Now().Format(time.RFC3339),
+		"previous_job_id": w.previousJob,
+	}
+	if err := database.AddPage(w.ctx, page, metadata); err != nil && w.ctx.Err() == nil {
+		log.Printf("❌ Failed to record gone page %s: %v", item.URL, err)
+	}
+}
+
+// storeVanished records the previous crawl's pages that a completed job neither stored nor fetched as gone,
+// so pages removed from the site are reported without incremental crawling too
+func (w *Worker) storeVanished() {
+	if len(w.previous) == 0 {
+		return
+	}
+	storedURLs, err := database.GetStoredURLs(context.Background(), w.JobID)
+	if err != nil {
+		log.Printf("⚠️ Failed to find the gone pages of job %d: %v", w.JobID, err)
+		return
+	}
+	entries, err := database.GetFrontier(context.Background(), w.JobID)
+	if err != nil {
+		log.Printf("⚠️ Failed to find the gone pages of job %d: %v", w.JobID, err)
+		return
+	}
+
+	gone := w.vanishedPages(storedURLs, entries)
+	if err := database.AddGonePages(gone); err != nil {
+		log.Printf("❌ Failed to record gone pages of job %d: %v", w.JobID, err)
+	}
+}
+
+// vanishedPages builds the gone pages for the previous crawl's pages missing from the job's stored URLs and
+// frontier. Only a crawl that ran out of URLs to visit shows a page is no longer linked; one stopped by
+// MaxLinks reports nothing. Pages deeper than MaxDepth aren't expected to be reached and are left out.
+func (w *Worker) vanishedPages(storedURLs []string, entries []database.FrontierEntry) []database.Page {
+	w.mu.Lock()
+	capped := w.counter >= w.Config.MaxLinks
+	w.mu.Unlock()
+	if capped {
+		return nil
+	}
+
+	// URLs the job stored, or fetched without storing them (duplicates, failures), aren't known to be gone
+	seen := make(map[string]bool, len(storedURLs)+len(entries))
+	for _, storedURL := range storedURLs {
+		seen[storedURL] = true
+	}
+	for _, entry := range entries {
+		if entry.State == database.FrontierQueued {
+			return nil // The crawl stopped before getting to it
+		}
+		seen[entry.URL] = true
+	}
+
+	metadataJSON, _ := json.Marshal(map[string]interface{}{"previous_job_id": w.previousJob})
+	var gone []database.Page
+	for _, previous := range w.previous {
+		if seen[previous.URL] || (w.Config.MaxDepth > 0 && previous.Depth > w.Config.MaxDepth) {
+			continue
+		}
+		gone = append(gone, database.Page{
+			JobID:        w.JobID,
+			URL:          previous.URL,
+			Depth:        previous.Depth,
+			Source:       SourcePrevious,
+			Metadata:     metadataJSON,
+			ContentHash:  previous.ContentHash,
+			ChangeStatus: database.ChangeGone,
+		})
+	}
+	return gone
+}
+
+// contentHash fingerprints a page's title and text, ignoring whitespace changes
+func contentHash(title, content string) string {
+	sum := sha256.Sum256([]byte(strings.Join(strings.Fields(title+"\n"+content), " ")))
+	return hex.EncodeToString(sum[:])
+}
+
+// firstNonEmpty returns the first of its arguments that is not empty
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,68 @@
+package worker
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"worker/database"
+)
+
+func TestVanishedPages(t *testing.T) {
+	previous := map[string]database.Page{
+		"https://example.com/":        {URL: "https://example.com/", Depth: 0},
+		"https://example.com/kept":    {URL: "https://example.com/kept", Depth: 1},
+		"https://example.com/failed":  {URL: "https://example.com/failed", Depth: 1},
+		"https://example.com/removed": {URL: "https://example.com/removed", Depth: 1},
+		"https://example.com/deep":    {URL: "https://example.com/deep", Depth: 3},
+	}
+	stored := []string{"https://example.com/", "https://example.com/kept"}
+	done := func(urls ...string) []database.FrontierEntry {
+		var entries []database.FrontierEntry
+		for _, u := range urls {
+			entries = append(entries, database.FrontierEntry{URL: u, State: database.FrontierDone})
+		}
+		return entries
+	}
+
+	tests := []struct {
+		name     string
+		maxLinks int
+		counter  int
+		entries  []database.FrontierEntry
+		want     []string
+	}{
+		{
+			"frontier used up", 10, 3,
+			done("https://example.com/", "https://example.com/kept", "https://example.com/failed"),
+			[]string{"https://example.com/removed"},
+		},
+		{
+			"capped by max_links", 3, 3,
+			done("https://example.com/", "https://example.com/kept", "https://example.com/failed"),
+			nil,
+		},
+		{
+			"queued entries left", 10, 2,
+			append(done("https://example.com/", "https://example.com/kept"),
+				database.FrontierEntry{URL: "https://example.com/failed", State: database.FrontierQueued}),
+			nil,
+		},
+	}
+	for _, test := range tests {
+		w := NewWorker(1, "https://example.com/", WorkerConfig{MaxLinks: test.maxLinks, MaxDepth: 2}, nil)
+		w.previous, w.previousJob, w.counter = previous, 7, test.counter
+
+		var got []string
+		for _, page := range w.vanishedPages(stored, test.entries) {
+			if page.ChangeStatus != database.ChangeGone || page.JobID != 1 {
+				t.Errorf("%s: vanished page %+v is not a gone page of the job", test.name, page)
+			}
+			got = append(got, page.URL)
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: gone pages = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
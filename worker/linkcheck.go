@@ -0,0 +1,160 @@
+package worker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"worker/database"
+)
+
+// maxExternalChecks caps the external link targets checked per job
+const maxExternalChecks = 1000
+
+// recordRedirects stores the redirect chain behind a response. If the client stopped following
+// redirects, resp is the last redirect response and err says why.
+func (w *Worker) recordRedirects(urlStr string, resp *http.Response, err error) {
+	if resp == nil {
+		return
+	}
+
+	hops := redirectChain(resp)
+	redirect := &database.Redirect{JobID: w.JobID, URL: urlStr}
+	if _, unfollowed := unfollowedRedirect(resp); err != nil || unfollowed {
+		hops = append(hops, redirectHop{URL: resp.Request.URL.String(), Status: resp.StatusCode})
+		if location, locationErr := resp.Location(); locationErr == nil {
+			redirect.FinalURL = location.String()
+		}
+		redirect.Loop = errors.Is(err, errRedirectLoop)
+	} else {
+		if len(hops) == 0 {
+			return
+		}
+		redirect.FinalURL = resp.Request.URL.String()
+		redirect.FinalStatus = resp.StatusCode
+	}
+
+	hopsJSON, err := json.Marshal(hops)
+	if err != nil {
+		return
+	}
+	redirect.Hops = hopsJSON
+	if err := database.SaveRedirect(w.ctx, redirect); err != nil && w.ctx.Err() == nil {
+		log.Printf("⚠️ Failed to record redirects of %s: %v", urlStr, err)
+	}
+}
+
+// checkExternalLinks requests the job's external link targets, most linked first, to find broken ones
+// without crawling them. Targets checked before the job was paused are not checked again.
+func (w *Worker) checkExternalLinks() {
+	targets, err := database.GetExternalTargets(w.JobID, maxExternalChecks)
+	if err != nil {
+		log.Printf("⚠️ Failed to list external links for job %d: %v", w.JobID, err)
+		return
+	}
+	if len(targets) == 0 {
+		return
+	}
+	log.Printf("🔗 Checking %d external links for job %d", len(targets), w.JobID)
+	w.report(fmt.Sprintf("Checking %d external links", len(targets)))
+
+	queue := make(chan database.ExternalTarget)
+	var wg sync.WaitGroup
+	for i := 0; i < w.Config.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for target := range queue {
+				w.checkLink(target)
+			}
+		}()
+	}
+	for _, target := range targets {
+		if w.ctx.Err() != nil || w.Paused() {
+			break
+		}
+		queue <- target
+	}
+	close(queue)
+	wg.Wait()
+}
+
+// checkLink requests an external link target as written with HEAD, falling back to GET for servers
+// that don't allow HEAD, and records the outcome. Transient failures are retried like crawl fetches.
+func (w *Worker) checkLink(target database.ExternalTarget) {
+	href := target.Href
+	if href == "" {
+		href = target.TargetURL
+	}
+	u, err := url.Parse(href)
+	if err != nil {
+		return
+	}
+
+	check := &database.LinkCheck{JobID: w.JobID, URL: target.TargetURL}
+	for attempt := 1; ; attempt++ {
+		resp, err := w.probe("HEAD", target.TargetURL, u)
+		if err == nil && (resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented) {
+			resp, err = w.probe("GET", target.TargetURL, u)
+		}
+		if w.ctx.Err() != nil {
+			return
+		}
+
+		var fetchErr *FetchError
+		if err != nil {
+			fetchErr = classifyError(err)
+		} else if resp.StatusCode >= 400 {
+			fetchErr = statusError(resp)
+		}
+		if resp != nil {
+			check.StatusCode = resp.StatusCode
+		}
+		if fetchErr == nil || attempt > w.Config.MaxRetries || !fetchErr.Retryable() {
+			if fetchErr != nil {
+				check.ErrorClass, check.Error = string(fetchErr.Class), fetchErr.Error()
+			}
+			break
+		}
+
+		timer := time.NewTimer(w.retryDelay(attempt, fetchErr))
+		select {
+		case <-timer.C:
+		case <-w.ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+
+	if err := database.AddLinkCheck(w.ctx, check); err != nil && w.ctx.Err() == nil {
+		log.Printf("❌ Failed to record link check of %s: %v", target.TargetURL, err)
+	}
+}
+
+// probe sends a request to a link target without reading the body, recording redirects under urlStr.
+// Robots.txt of external hosts isn't fetched, so only the job's request delay applies.
+func (w *Worker) probe(method, urlStr string, u *url.URL) (*http.Response, error) {
+	req, err := w.newRequest(method, u.String())
+	if err != nil {
+		return nil, err
+	}
+
+	release, err := politeness.acquire(w.ctx, u.Host, w.Config.RequestDelay, w.Config.MaxConnsPerHost)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := w.client.Do(req)
+	w.recordRedirects(urlStr, resp, err)
+	if err != nil {
+		release(nil)
+		return nil, err
+	}
+	resp.Body.Close()
+	release(resp)
+	return resp, nil
+}
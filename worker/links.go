@@ -0,0 +1,66 @@
+package worker
+
+import (
+	"log"
+	"net/url"
+	"strings"
+
+	"worker/database"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// maxAnchorLength caps the anchor text stored per link
+const maxAnchorLength = 300
+
+// pageLink builds the links table row for an <a href> on a stored page, returning false for non-web links
+func (w *Worker) pageLink(page *database.Page, base *url.URL, a *goquery.Selection, href string) (database.Link, bool) {
+	parsedURL, err := url.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return database.Link{}, false
+	}
+	resolvedURL := base.ResolveReference(parsedURL)
+	if scheme := strings.ToLower(resolvedURL.Scheme); scheme != "http" && scheme != "https" {
+		return database.Link{}, false
+	}
+	target := normalizeURL(resolvedURL, w.Config.Normalize)
+	resolvedURL.Fragment, resolvedURL.RawFragment = "", ""
+
+	anchor := normalizeSpace(a.Text())
+	if anchor == "" {
+		anchor = normalizeSpace(a.Find("img[alt]").AttrOr("alt", ""))
+	}
+	if runes := []rune(anchor); len(runes) > maxAnchorLength {
+		anchor = string(runes[:maxAnchorLength])
+	}
+
+	link := database.Link{
+		JobID:     w.JobID,
+		PageID:    page.ID,
+		SourceURL: page.URL,
+		TargetURL: target.String(),
+		Anchor:    anchor,
+		Internal:  w.scope.internal(target),
+	}
+	if written := resolvedURL.String(); written != link.TargetURL {
+		link.Href = written
+	}
+	for _, rel := range strings.Fields(strings.ToLower(a.AttrOr("rel", ""))) {
+		switch rel {
+		case "nofollow":
+			link.Nofollow = true
+		case "sponsored":
+			link.Sponsored = true
+		case "ugc":
+			link.UGC = true
+		}
+	}
+	return link, true
+}
+
+// storeLinks records the links found on a page
+func (w *Worker) storeLinks(links []database.Link) {
+	if err := database.AddLinks(w.ctx, links); err != nil && w.ctx.Err() == nil {
+		log.Printf("⚠️ Failed to store links for job %d: %v", w.JobID, err)
+	}
+}
@@ -0,0 +1,309 @@
+package worker
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// maxMetadataText caps text values taken from the page body, such as a microdata articleBody
+const maxMetadataText = 1000
+
+// metadataHeaders are the response headers copied into page metadata
+var metadataHeaders = []string{
+	"Cache-Control", "Content-Encoding", "Content-Language", "Content-Length", "Content-Type", "ETag",
+	"Last-Modified", "Link", "Server", "Strict-Transport-Security", "Vary", "X-Powered-By", "X-Robots-Tag",
+}
+
+// openGraphPrefixes are the <meta property> namespaces collected as OpenGraph tags
+var openGraphPrefixes = []string{"og:", "fb:", "article:", "book:", "profile:", "product:", "music:", "video:"}
+
+// extractMetadata adds what a page declares about itself and the server's headers of interest to metadata.
+// URLs are made absolute against base; keys without a value are left out.
+func extractMetadata(doc *goquery.Document, resp *http.Response, base *url.URL, metadata map[string]interface{}) {
+	set := func(key string, value interface{}) {
+		switch v := value.(type) {
+		case string:
+			if v == "" {
+				return
+			}
+		case []string:
+			if len(v) == 0 {
+				return
+			}
+		case []interface{}:
+			if len(v) == 0 {
+				return
+			}
+		case map[string]interface{}:
+			if len(v) == 0 {
+				return
+			}
+		}
+		metadata[key] = value
+	}
+
+	// <meta name> and <meta property> values, names lowercased
+	names := make(map[string][]string)
+	properties := make(map[string][]string)
+	var httpEquivLanguage string
+	doc.Find("meta[content]").Each(func(_ int, s *goquery.Selection) {
+		content := strings.TrimSpace(s.AttrOr("content", ""))
+		if name := strings.ToLower(strings.TrimSpace(s.AttrOr("name", ""))); name != "" {
+			names[name] = append(names[name], content)
+		}
+		if property := strings.ToLower(strings.TrimSpace(s.AttrOr("property", ""))); property != "" {
+			properties[property] = append(properties[property], content)
+		}
+		if strings.EqualFold(s.AttrOr("http-equiv", ""), "content-language") {
+			httpEquivLanguage = content
+		}
+	})
+
+	set("description", first(names["description"]))
+	if keywords := first(names["keywords"]); keywords != "" {
+		var list []string
+		for _, keyword := range strings.Split(keywords, ",") {
+			if keyword = strings.TrimSpace(keyword); keyword != "" {
+				list = append(list, keyword)
+			}
+		}
+		set("keywords", list)
+	}
+
+	robots := make(map[string]interface{})
+	for _, name := range []string{"robots", "googlebot", "bingbot"} {
+		if value := first(names[name]); value != "" {
+			robots[name] = value
+		}
+	}
+	set("robots_meta", robots)
+
+	if href, exists := linkWithRel(doc, "canonical").Attr("href"); exists {
+		set("canonical", resolveHref(base, href))
+	}
+
+	var alternates []interface{}
+	linkWithRel(doc, "alternate").Each(func(_ int, s *goquery.Selection) {
+		lang, hasLang := s.Attr("hreflang")
+		href, hasHref := s.Attr("href")
+		if hasLang && hasHref {
+			alternates = append(alternates, map[string]interface{}{"lang": strings.TrimSpace(lang), "href": resolveHref(base, href)})
+		}
+	})
+	set("hreflang", alternates)
+
+	openGraph := make(map[string][]string)
+	twitter := make(map[string][]string)
+	for property, values := range properties {
+		for _, prefix := range openGraphPrefixes {
+			if strings.HasPrefix(property, prefix) {
+				openGraph[property] = values
+			}
+		}
+		if strings.HasPrefix(property, "twitter:") {
+			twitter[property] = values // Some sites use property instead of name
+		}
+	}
+	for name, values := range names {
+		if strings.HasPrefix(name, "twitter:") {
+			twitter[name] = append(twitter[name], values...)
+		}
+	}
+	set("opengraph", collapseValues(openGraph))
+	set("twitter", collapseValues(twitter))
+
+	language := strings.TrimSpace(doc.Find("html").AttrOr("lang", ""))
+	if language == "" {
+		language = firstNonEmpty(httpEquivLanguage, resp.Header.Get("Content-Language"))
+	}
+	set("language", language)
+
+	set("favicon", favicon(doc, base))
+	set("json_ld", jsonLD(doc))
+	set("microdata", microdata(doc, base))
+	set("rdfa", rdfa(doc, base))
+
+	headers := make(map[string]interface{})
+	for _, name := range metadataHeaders {
+		if values := resp.Header.Values(name); len(values) > 0 {
+			headers[strings.ToLower(name)] = strings.Join(values, ", ")
+		}
+	}
+	set("headers", headers)
+}
+
+// linkWithRel selects the <link> elements whose rel includes a token, ignoring case
+func linkWithRel(doc *goquery.Document, token string) *goquery.Selection {
+	return doc.Find("link[rel]").FilterFunction(func(_ int, s *goquery.Selection) bool {
+		for _, rel := range strings.Fields(strings.ToLower(s.AttrOr("rel", ""))) {
+			if rel == token {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// favicon returns the page's declared icon, or /favicon.ico where browsers look without one
+func favicon(doc *goquery.Document, base *url.URL) string {
+	for _, rel := range []string{"icon", "apple-touch-icon"} {
+		if href, exists := linkWithRel(doc, rel).Attr("href"); exists && strings.TrimSpace(href) != "" {
+			return resolveHref(base, href)
+		}
+	}
+	return resolveHref(base, "/favicon.ico")
+}
+
+// jsonLD parses the page's JSON-LD blocks, skipping those that aren't valid JSON
+func jsonLD(doc *goquery.Document) []interface{} {
+	var blocks []interface{}
+	doc.Find(`script[type="application/ld+json"]`).Each(func(_ int, s *goquery.Selection) {
+		var block interface{}
+		if err := json.Unmarshal([]byte(strings.TrimSpace(s.Text())), &block); err == nil {
+			blocks = append(blocks, block)
+		}
+	})
+	return blocks
+}
+
+// microdata collects the page's top-level itemscope items with their itemprop values
+func microdata(doc *goquery.Document, base *url.URL) []interface{} {
+	var items []interface{}
+	doc.Find("[itemscope]").Each(func(_ int, s *goquery.Selection) {
+		if _, nested := s.Attr("itemprop"); !nested {
+			items = append(items, structuredItem(s, base, "itemscope", "itemprop", "itemtype", "itemid"))
+		}
+	})
+	return items
+}
+
+// rdfa collects the page's top-level typeof resources with their property values (RDFa Lite)
+func rdfa(doc *goquery.Document, base *url.URL) []interface{} {
+	var items []interface{}
+	doc.Find("[typeof]").Each(func(_ int, s *goquery.Selection) {
+		if s.ParentsFiltered("[typeof]").Length() == 0 {
+			item := structuredItem(s, base, "typeof", "property", "typeof", "resource")
+			if vocab := s.Closest("[vocab]").AttrOr("vocab", ""); vocab != "" {
+				item["vocab"] = vocab
+			}
+			items = append(items, item)
+		}
+	})
+	return items
+}
+
+// structuredItem reads a microdata or RDFa item: its type, id and the properties declared inside it,
+// with nested items as values
+func structuredItem(s *goquery.Selection, base *url.URL, scopeAttr, propAttr, typeAttr, idAttr string) map[string]interface{} {
+	item := make(map[string]interface{})
+	if types := strings.Fields(s.AttrOr(typeAttr, "")); len(types) == 1 {
+		item["type"] = types[0]
+	} else if len(types) > 1 {
+		item["type"] = types
+	}
+	if id := s.AttrOr(idAttr, ""); id != "" {
+		item["id"] = resolveHref(base, id)
+	}
+
+	properties := make(map[string][]interface{})
+	var walk func(*goquery.Selection)
+	walk = func(parent *goquery.Selection) {
+		parent.Children().Each(func(_ int, child *goquery.Selection) {
+			_, scoped := child.Attr(scopeAttr)
+			if names := strings.Fields(child.AttrOr(propAttr, "")); len(names) > 0 {
+				var value interface{}
+				if scoped {
+					value = structuredItem(child, base, scopeAttr, propAttr, typeAttr, idAttr)
+				} else {
+					value = propertyValue(child, base)
+				}
+				for _, name := range names {
+					properties[name] = append(properties[name], value)
+				}
+			}
+			if !scoped {
+				walk(child) // A nested item's properties are its own
+			}
+		})
+	}
+	walk(s)
+
+	values := make(map[string]interface{}, len(properties))
+	for name, list := range properties {
+		if len(list) == 1 {
+			values[name] = list[0]
+		} else {
+			values[name] = list
+		}
+	}
+	item["properties"] = values
+	return item
+}
+
+// propertyValue reads a microdata or RDFa property value the way the specs define it per element
+func propertyValue(s *goquery.Selection, base *url.URL) string {
+	if content, exists := s.Attr("content"); exists {
+		return strings.TrimSpace(content)
+	}
+	switch goquery.NodeName(s) {
+	case "a", "area", "link":
+		return resolveHref(base, s.AttrOr("href", ""))
+	case "img", "audio", "video", "source", "iframe", "embed", "track":
+		return resolveHref(base, s.AttrOr("src", ""))
+	case "object":
+		return resolveHref(base, s.AttrOr("data", ""))
+	case "time":
+		if datetime, exists := s.Attr("datetime"); exists {
+			return strings.TrimSpace(datetime)
+		}
+	case "data", "meter":
+		return strings.TrimSpace(s.AttrOr("value", ""))
+	}
+	if resource, exists := s.Attr("resource"); exists {
+		return resolveHref(base, resource)
+	}
+
+	text := []rune(normalizeSpace(s.Text()))
+	if len(text) > maxMetadataText {
+		text = text[:maxMetadataText]
+	}
+	return string(text)
+}
+
+// collapseValues turns repeated tags into lists and single ones into plain strings
+func collapseValues(values map[string][]string) map[string]interface{} {
+	collapsed := make(map[string]interface{}, len(values))
+	for key, list := range values {
+		if len(list) == 1 {
+			collapsed[key] = list[0]
+		} else {
+			collapsed[key] = list
+		}
+	}
+	return collapsed
+}
+
+// resolveHref makes a URL from the page absolute, returning it unchanged if it can't be parsed
+func resolveHref(base *url.URL, href string) string {
+	href = strings.TrimSpace(href)
+	if base == nil || href == "" {
+		return href
+	}
+	resolved, err := base.Parse(href)
+	if err != nil {
+		return href
+	}
+	return resolved.String()
+}
+
+// first returns the first of a list of values, or ""
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
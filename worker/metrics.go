@@ -0,0 +1,178 @@
+package worker
+
+import (
+	"math"
+
+	"worker/database"
+)
+
+const (
+	pageRankDamping    = 0.85
+	pageRankIterations = 100
+	pageRankTolerance  = 1e-9 // Stop once the ranks change less than this in total
+)
+
+// storeLinkMetrics computes the link metrics of the job's pages from its links table and stores them on the pages
+func (w *Worker) storeLinkMetrics() error {
+	nodes, err := database.GetPageNodes(w.JobID)
+	if err != nil || len(nodes) == 0 {
+		return err
+	}
+	links, err := database.GetLinks(w.JobID)
+	if err != nil {
+		return err
+	}
+	redirects, err := database.GetRedirects(w.JobID)
+	if err != nil {
+		return err
+	}
+
+	// Links to a URL that redirected count for the page the redirect ended on
+	finalURLs := make(map[string]string, len(redirects))
+	for _, redirect := range redirects {
+		if redirect.FinalStatus != 0 {
+			finalURLs[redirect.URL] = redirect.FinalURL
+		}
+	}
+	start := normalizeURL(w.base, w.Config.Normalize).String()
+	return database.UpdatePageMetrics(w.JobID, linkMetrics(nodes, links, finalURLs, start))
+}
+
+// linkMetrics computes PageRank, degrees, click depth and orphan and dead-end flags over the internal links
+// between stored pages. Every link counts towards the degrees and click depth, but nofollow links pass no PageRank.
+func linkMetrics(nodes []database.PageNode, links []database.Link, finalURLs map[string]string, start string) map[uint]database.PageMetrics {
+	byURL := make(map[string]int, len(nodes))
+	byID := make(map[uint]int, len(nodes))
+	for i, node := range nodes {
+		byURL[node.URL] = i
+		byID[node.ID] = i
+	}
+	for i, node := range nodes {
+		if _, taken := byURL[node.FetchedURL]; node.FetchedURL != "" && !taken {
+			byURL[node.FetchedURL] = i
+		}
+	}
+	resolve := func(target string) (int, bool) {
+		if i, ok := byURL[target]; ok {
+			return i, true
+		}
+		i, ok := byURL[finalURLs[target]]
+		return i, ok
+	}
+
+	n := len(nodes)
+	targets := make([]map[string]bool, n) // Distinct internal URLs each page links to
+	linked := make([]map[int]bool, n)     // Pages linking to each page
+	clicks := make([][]int, n)            // Pages each page links to
+	followed := make([][]int, n)          // Pages each page passes PageRank to
+	seen := make(map[[2]int]bool)
+	seenFollowed := make(map[[2]int]bool)
+	for i := range nodes {
+		targets[i] = make(map[string]bool)
+		linked[i] = make(map[int]bool)
+	}
+
+	for _, link := range links {
+		from, ok := byID[link.PageID]
+		if !ok || !link.Internal {
+			continue
+		}
+		to, resolved := resolve(link.TargetURL)
+		if (resolved && to == from) || link.TargetURL == nodes[from].URL {
+			continue
+		}
+		if !resolved {
+			targets[from][link.TargetURL] = true
+			continue
+		}
+		targets[from][nodes[to].URL] = true
+		linked[to][from] = true
+
+		edge := [2]int{from, to}
+		if !seen[edge] {
+			seen[edge] = true
+			clicks[from] = append(clicks[from], to)
+		}
+		if !link.Nofollow && !seenFollowed[edge] {
+			seenFollowed[edge] = true
+			followed[from] = append(followed[from], to)
+		}
+	}
+
+	ranks := pageRank(followed)
+	depths := clickDepths(clicks, start, resolve)
+
+	metrics := make(map[uint]database.PageMetrics, n)
+	for i, node := range nodes {
+		metrics[node.ID] = database.PageMetrics{
+			PageRank:   ranks[i],
+			InDegree:   len(linked[i]),
+			OutDegree:  len(targets[i]),
+			ClickDepth: depths[i],
+			Orphan:     node.Source == SourceSitemap && len(linked[i]) == 0,
+			NoOutlinks: len(targets[i]) == 0,
+		}
+	}
+	return metrics
+}
+
+// pageRank iterates PageRank over an adjacency list. Pages without outlinks spread their rank over every page.
+func pageRank(outlinks [][]int) []float64 {
+	n := len(outlinks)
+	ranks := make([]float64, n)
+	for i := range ranks {
+		ranks[i] = 1 / float64(n)
+	}
+
+	for iteration := 0; iteration < pageRankIterations; iteration++ {
+		next := make([]float64, n)
+		dangling := 0.0
+		for i, targets := range outlinks {
+			if len(targets) == 0 {
+				dangling += ranks[i]
+				continue
+			}
+			share := ranks[i] / float64(len(targets))
+			for _, j := range targets {
+				next[j] += share
+			}
+		}
+
+		base := (1-pageRankDamping)/float64(n) + pageRankDamping*dangling/float64(n)
+		delta := 0.0
+		for i := range next {
+			next[i] = base + pageRankDamping*next[i]
+			delta += math.Abs(next[i] - ranks[i])
+		}
+		ranks = next
+		if delta < pageRankTolerance {
+			break
+		}
+	}
+	return ranks
+}
+
+// clickDepths finds the fewest clicks from the start page to every page, nil for pages it can't reach
+func clickDepths(clicks [][]int, start string, resolve func(string) (int, bool)) []*int {
+	depths := make([]*int, len(clicks))
+	first, ok := resolve(start)
+	if !ok {
+		return depths
+	}
+
+	zero := 0
+	depths[first] = &zero
+	queue := []int{first}
+	for len(queue) > 0 {
+		page := queue[0]
+		queue = queue[1:]
+		for _, next := range clicks[page] {
+			if depths[next] == nil {
+				depth := *depths[page] + 1
+				depths[next] = &depth
+				queue = append(queue, next)
+			}
+		}
+	}
+	return depths
+}
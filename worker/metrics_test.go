@@ -0,0 +1,126 @@
+package worker
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"worker/database"
+)
+
+func TestPageRank(t *testing.T) {
+	tests := []struct {
+		name     string
+		outlinks [][]int
+		want     []float64
+	}{
+		{"single page", [][]int{nil}, []float64{1}},
+		{"no links", [][]int{nil, nil, nil, nil}, []float64{0.25, 0.25, 0.25, 0.25}},
+		{"cycle", [][]int{{1}, {2}, {0}}, []float64{1.0 / 3, 1.0 / 3, 1.0 / 3}},
+		{"dangling page", [][]int{{1}, nil}, []float64{0.075 / 0.21375, 0.13875 / 0.21375}},
+		{"hub", [][]int{{1}, {0}, {0}, {0}}, []float64{0.133125 / 0.2775, 0.0375 + 0.85*0.133125/0.2775, 0.0375, 0.0375}},
+	}
+	for _, test := range tests {
+		ranks := pageRank(test.outlinks)
+		if len(ranks) != len(test.want) {
+			t.Fatalf("%s: pageRank returned %d ranks, want %d", test.name, len(ranks), len(test.want))
+		}
+		sum := 0.0
+		for i, rank := range ranks {
+			sum += rank
+			if math.Abs(rank-test.want[i]) > 1e-4 {
+				t.Errorf("%s: rank of page %d = %.6f, want %.6f", test.name, i, rank, test.want[i])
+			}
+		}
+		if math.Abs(sum-1) > 1e-9 {
+			t.Errorf("%s: ranks sum to %f, want 1", test.name, sum)
+		}
+	}
+}
+
+func TestClickDepths(t *testing.T) {
+	urls := map[string]int{"/": 0, "/a": 1, "/b": 2, "/c": 3, "/island": 4}
+	resolve := func(target string) (int, bool) {
+		i, ok := urls[target]
+		return i, ok
+	}
+	depth := func(d int) *int { return &d }
+
+	tests := []struct {
+		name   string
+		clicks [][]int
+		start  string
+		want   []*int
+	}{
+		{"chain", [][]int{{1}, {2}, {3}, nil, nil}, "/", []*int{depth(0), depth(1), depth(2), depth(3), nil}},
+		{"shortest path", [][]int{{1, 3}, {2}, {3}, nil, nil}, "/", []*int{depth(0), depth(1), depth(2), depth(1), nil}},
+		{"cycle", [][]int{{1}, {0, 2}, {1}, nil, {0}}, "/", []*int{depth(0), depth(1), depth(2), nil, nil}},
+		{"other start", [][]int{{1}, {2}, nil, nil, {0}}, "/island", []*int{depth(1), depth(2), depth(3), nil, depth(0)}},
+		{"start not stored", [][]int{{1}, nil, nil, nil, nil}, "/missing", []*int{nil, nil, nil, nil, nil}},
+	}
+	for _, test := range tests {
+		if got := clickDepths(test.clicks, test.start, resolve); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: clickDepths = %v, want %v", test.name, derefDepths(got), derefDepths(test.want))
+		}
+	}
+}
+
+// derefDepths shows click depths as numbers, -1 for unreachable pages
+func derefDepths(depths []*int) []int {
+	values := make([]int, len(depths))
+	for i, d := range depths {
+		values[i] = -1
+		if d != nil {
+			values[i] = *d
+		}
+	}
+	return values
+}
+
+func TestLinkMetrics(t *testing.T) {
+	nodes := []database.PageNode{
+		{ID: 1, URL: "https://example.com/", Source: SourceSeed},
+		{ID: 2, URL: "https://example.com/a", Source: SourceLink},
+		{ID: 3, URL: "https://example.com/canonical", Source: SourceLink, FetchedURL: "https://example.com/b"},
+		{ID: 4, URL: "https://example.com/orphan", Source: SourceSitemap},
+	}
+	link := func(from uint, target string, nofollow bool) database.Link {
+		return database.Link{PageID: from, TargetURL: target, Internal: true, Nofollow: nofollow}
+	}
+	links := []database.Link{
+		link(1, "https://example.com/a", false),
+		link(1, "https://example.com/a", false), // Counted once
+		link(1, "https://example.com/old", false),
+		link(1, "https://example.com/", false), // Itself
+		link(2, "https://example.com/b", true),
+		link(2, "https://example.com/missing", false),
+		{PageID: 2, TargetURL: "https://other.com/", Internal: false},
+	}
+	finalURLs := map[string]string{"https://example.com/old": "https://example.com/canonical"}
+
+	metrics := linkMetrics(nodes, links, finalURLs, "https://example.com/")
+	depth := func(d int) *int { return &d }
+	want := map[uint]struct {
+		in, out    int
+		clickDepth *int
+		orphan     bool
+		noOutlinks bool
+	}{
+		1: {0, 2, depth(0), false, false},
+		2: {1, 2, depth(1), false, false},
+		3: {2, 0, depth(1), false, true},
+		4: {0, 0, nil, true, true},
+	}
+	for id, w := range want {
+		got := metrics[id]
+		if got.InDegree != w.in || got.OutDegree != w.out || !reflect.DeepEqual(got.ClickDepth, w.clickDepth) ||
+			got.Orphan != w.orphan || got.NoOutlinks != w.noOutlinks {
+			t.Errorf("page %d metrics = %+v, want in %d, out %d, click depth %v, orphan %v, no outlinks %v",
+				id, got, w.in, w.out, derefDepths([]*int{w.clickDepth}), w.orphan, w.noOutlinks)
+		}
+	}
+	// The nofollow link to page 3 passes no rank, but the redirected link does
+	if metrics[3].PageRank <= metrics[4].PageRank {
+		t.Errorf("page 3 rank %f should exceed the orphan's %f", metrics[3].PageRank, metrics[4].PageRank)
+	}
+}
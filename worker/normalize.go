@@ -0,0 +1,140 @@
+package worker
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// NormalizeConfig controls how URLs are canonicalized before they are deduplicated.
+// Scheme and host are always lowercased, default ports removed and dot segments resolved.
+type NormalizeConfig struct {
+	KeepFragments     bool     `json:"keep_fragments"`      // Treat #fragments as distinct pages
+	KeepQueryOrder    bool     `json:"keep_query_order"`    // Don't sort query parameters
+	KeepTrailingSlash bool     `json:"keep_trailing_slash"` // Treat /a and /a/ as distinct pages
+	CaseInsensitive   bool     `json:"case_insensitive"`    // Lowercase paths, for hosts that ignore case
+	TrackingParams    []string `json:"tracking_params"`     // Query parameters to drop, "*" suffix matches a prefix (nil = defaults)
+	IgnoreCanonical   bool     `json:"ignore_canonical"`    // Don't collapse pages onto their <link rel="canonical">
+}
+
+// defaultTrackingParams are dropped from query strings unless a job provides its own list
+var defaultTrackingParams = []string{
+	"utm_*", "gclid", "gclsrc", "dclid", "fbclid", "msclkid", "yclid",
+	"mc_cid", "mc_eid", "_ga", "_gl", "igshid", "_hsenc", "_hsmi",
+}
+
+// normalizeURL returns the canonical form of an absolute URL used as its dedup key.
+// Pages are requested as written, since servers may answer the normalized form differently.
+func normalizeURL(u *url.URL, config NormalizeConfig) *url.URL {
+	n := *u
+	n.Scheme = strings.ToLower(n.Scheme)
+
+	// Lowercase the host and drop ports that match the scheme's default
+	host, port := strings.ToLower(n.Hostname()), n.Port()
+	if (n.Scheme == "http" && port == "80") || (n.Scheme == "https" && port == "443") {
+		port = ""
+	}
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]" // IPv6 literal
+	}
+	if port != "" {
+		host += ":" + port
+	}
+	n.Host = host
+
+	escapedPath := removeDotSegments(n.EscapedPath())
+	if config.CaseInsensitive {
+		escapedPath = strings.ToLower(escapedPath)
+	}
+	if !config.KeepTrailingSlash && len(escapedPath) > 1 {
+		escapedPath = strings.TrimRight(escapedPath, "/")
+	}
+	if escapedPath == "" {
+		escapedPath = "/"
+	}
+	if path, err := url.PathUnescape(escapedPath); err == nil {
+		n.Path, n.RawPath = path, escapedPath
+	}
+
+	if !config.KeepFragments {
+		n.Fragment, n.RawFragment = "", ""
+	}
+
+	n.RawQuery = normalizeQuery(n.RawQuery, config)
+	n.ForceQuery = false
+	return &n
+}
+
+// normalizeQuery drops tracking parameters and sorts the rest, leaving their encoding untouched
+func normalizeQuery(rawQuery string, config NormalizeConfig) string {
+	if rawQuery == "" {
+		return ""
+	}
+
+	tracking := config.TrackingParams
+	if tracking == nil {
+		tracking = defaultTrackingParams
+	}
+
+	var params []string
+	for _, param := range strings.Split(rawQuery, "&") {
+		if param == "" {
+			continue
+		}
+		key, _, _ := strings.Cut(param, "=")
+		if name, err := url.QueryUnescape(key); err == nil && isTrackingParam(name, tracking) {
+			continue
+		}
+		params = append(params, param)
+	}
+
+	if !config.KeepQueryOrder {
+		sort.Strings(params)
+	}
+	return strings.Join(params, "&")
+}
+
+// isTrackingParam matches a query parameter name against the tracking list
+func isTrackingParam(name string, tracking []string) bool {
+	name = strings.ToLower(name)
+	for _, pattern := range tracking {
+		pattern = strings.ToLower(pattern)
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if name == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// removeDotSegments resolves "." and ".." path segments (RFC 3986 section 5.2.4)
+func removeDotSegments(path string) string {
+	if !strings.Contains(path, ".") {
+		return path
+	}
+
+	segments := strings.Split(path, "/")
+	out := make([]string, 0, len(segments))
+	for i, segment := range segments {
+		last := i == len(segments)-1
+		switch segment {
+		case ".":
+			if last {
+				out = append(out, "")
+			}
+		case "..":
+			if len(out) > 1 {
+				out = out[:len(out)-1]
+			}
+			if last {
+				out = append(out, "")
+			}
+		default:
+			out = append(out, segment)
+		}
+	}
+	return strings.Join(out, "/")
+}
@@ -0,0 +1,74 @@
+package worker
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		name   string
+		in     string
+		config NormalizeConfig
+		want   string
+	}{
+		{"scheme and host case", "HTTPS://Example.COM/Path", NormalizeConfig{}, "https://example.com/Path"},
+		{"default http port", "http://example.com:80/a", NormalizeConfig{}, "http://example.com/a"},
+		{"default https port", "https://example.com:443/a", NormalizeConfig{}, "https://example.com/a"},
+		{"other port", "https://example.com:8443/a", NormalizeConfig{}, "https://example.com:8443/a"},
+		{"ipv6 host", "http://[::1]:80/a", NormalizeConfig{}, "http://[::1]/a"},
+		{"empty path", "https://example.com", NormalizeConfig{}, "https://example.com/"},
+		{"dot segments", "https://example.com/a/./b/../c", NormalizeConfig{}, "https://example.com/a/c"},
+		{"trailing slash", "https://example.com/docs/", NormalizeConfig{}, "https://example.com/docs"},
+		{"keep trailing slash", "https://example.com/docs/", NormalizeConfig{KeepTrailingSlash: true}, "https://example.com/docs/"},
+		{"root slash", "https://example.com/", NormalizeConfig{}, "https://example.com/"},
+		{"fragment", "https://example.com/a#section", NormalizeConfig{}, "https://example.com/a"},
+		{"keep fragment", "https://example.com/a#section", NormalizeConfig{KeepFragments: true}, "https://example.com/a#section"},
+		{"sorted query", "https://example.com/a?b=2&a=1", NormalizeConfig{}, "https://example.com/a?a=1&b=2"},
+		{"keep query order", "https://example.com/a?b=2&a=1", NormalizeConfig{KeepQueryOrder: true}, "https://example.com/a?b=2&a=1"},
+		{"tracking params", "https://example.com/a?utm_source=x&id=3&gclid=y&UTM_Medium=z", NormalizeConfig{}, "https://example.com/a?id=3"},
+		{"custom tracking params", "https://example.com/a?ref=x&utm_source=y", NormalizeConfig{TrackingParams: []string{"ref"}}, "https://example.com/a?utm_source=y"},
+		{"empty query", "https://example.com/a?", NormalizeConfig{}, "https://example.com/a"},
+		{"only tracking params", "https://example.com/a?fbclid=1", NormalizeConfig{}, "https://example.com/a"},
+		{"escaped query kept", "https://example.com/a?q=a%20b", NormalizeConfig{}, "https://example.com/a?q=a%20b"},
+		{"escaped path kept", "https://example.com/a%2Fb", NormalizeConfig{}, "https://example.com/a%2Fb"},
+		{"case sensitive path", "https://example.com/About", NormalizeConfig{}, "https://example.com/About"},
+		{"case insensitive path", "https://example.com/About", NormalizeConfig{CaseInsensitive: true}, "https://example.com/about"},
+	}
+	for _, test := range tests {
+		u, err := url.Parse(test.in)
+		if err != nil {
+			t.Fatalf("%s: url.Parse(%q): %v", test.name, test.in, err)
+		}
+		before := u.String()
+		if got := normalizeURL(u, test.config).String(); got != test.want {
+			t.Errorf("%s: normalizeURL(%q) = %q, want %q", test.name, test.in, got, test.want)
+		}
+		if got := u.String(); got != before {
+			t.Errorf("%s: normalizeURL modified its input to %q", test.name, got)
+		}
+	}
+}
+
+func TestResolveLink(t *testing.T) {
+	w := NewWorker(1, "https://example.com/docs/", WorkerConfig{}, nil)
+	base, _ := url.Parse("https://example.com/docs/")
+
+	tests := []struct {
+		href                string
+		normalized, written string
+	}{
+		{"guide/", "https://example.com/docs/guide", "https://example.com/docs/guide/"},
+		{"guide", "https://example.com/docs/guide", ""},
+		{"/search?b=2&a=1#results", "https://example.com/search?a=1&b=2", "https://example.com/search?b=2&a=1"},
+		{"page?utm_source=mail", "https://example.com/docs/page", "https://example.com/docs/page?utm_source=mail"},
+		{"https://other.com/", "", ""},
+		{"mailto:team@example.com", "", ""},
+	}
+	for _, test := range tests {
+		normalized, written := w.resolveLink(base, test.href)
+		if normalized != test.normalized || written != test.written {
+			t.Errorf("resolveLink(%q) = %q, %q, want %q, %q", test.href, normalized, written, test.normalized, test.written)
+		}
+	}
+}
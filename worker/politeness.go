@@ -0,0 +1,177 @@
+package worker
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMaxConnsPerHost = 2
+	maxCrawlDelay          = 60 * time.Second // Upper bound for robots.txt Crawl-delay
+	minBackoff             = time.Second      // First backoff after a 429/503 without Retry-After
+	maxBackoff             = 5 * time.Minute
+)
+
+// politeness is shared by every worker in the process so concurrent jobs against one site don't add up
+var politeness = newHostScheduler()
+
+// hostScheduler enforces a minimum delay and a connection limit per host. Jobs may ask for different limits;
+// a host gets the lowest limit among the jobs using it, so no job's limit is exceeded.
+type hostScheduler struct {
+	mu    sync.Mutex
+	hosts map[string]*hostState
+}
+
+// hostState tracks the requests made to a single host
+type hostState struct {
+	active  int           // Requests currently in flight
+	limits  map[int]int   // Connection limits of the requests waiting or in flight, with their counts
+	next    time.Time     // Earliest start time of the next request
+	backoff time.Duration // Extra delay after 429/503 responses, decays on success
+	changed chan struct{} // Closed and replaced whenever a slot is released
+}
+
+// newHostScheduler initializes an empty scheduler
+func newHostScheduler() *hostScheduler {
+	return &hostScheduler{hosts: make(map[string]*hostState)}
+}
+
+// acquire blocks until a request to host may start and returns the function that releases the slot.
+// It gives up with the context's error if ctx is canceled first.
+func (s *hostScheduler) acquire(ctx context.Context, host string, delay time.Duration, maxConns int) (func(resp *http.Response), error) {
+	if maxConns <= 0 {
+		maxConns = defaultMaxConnsPerHost
+	}
+
+	// Registering the limit keeps the host's state until the slot is released or given up
+	s.mu.Lock()
+	h, exists := s.hosts[host]
+	if !exists {
+		h = &hostState{limits: make(map[int]int), changed: make(chan struct{})}
+		s.hosts[host] = h
+	}
+	h.limits[maxConns]++
+	s.mu.Unlock()
+
+	for {
+		s.mu.Lock()
+		now := time.Now()
+		if h.active < h.limit() && !now.Before(h.next) {
+			h.active++
+			h.next = now.Add(delay + h.backoff)
+			s.mu.Unlock()
+			return func(resp *http.Response) { s.release(host, maxConns, resp) }, nil
+		}
+
+		changed := h.changed
+		wait := h.next.Sub(now)
+		s.mu.Unlock()
+
+		// Wait for the delay to elapse or for another request to free a slot
+		var timer *time.Timer
+		var timeout <-chan time.Time
+		if wait > 0 {
+			timer = time.NewTimer(wait)
+			timeout = timer.C
+		}
+		select {
+		case <-timeout:
+		case <-changed:
+		case <-ctx.Done():
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if err := ctx.Err(); err != nil {
+			s.mu.Lock()
+			s.unregister(host, h, maxConns)
+			s.mu.Unlock()
+			return nil, err
+		}
+	}
+}
+
+// release frees a slot and adapts the host's backoff to the response (nil on network errors)
+func (s *hostScheduler) release(host string, maxConns int, resp *http.Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	h := s.hosts[host]
+	h.active--
+
+	if resp != nil && (resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable) {
+		h.backoff = min(max(h.backoff*2, minBackoff), maxBackoff)
+		wait := h.backoff
+		if retryAfter := parseRetryAfter(resp.Header.Get("Retry-After")); retryAfter > 0 {
+			wait = min(retryAfter, maxBackoff)
+		}
+		if until := time.Now().Add(wait); until.After(h.next) {
+			h.next = until
+		}
+	} else if resp != nil && resp.StatusCode < 500 {
+		h.backoff /= 2
+		if h.backoff < 100*time.Millisecond {
+			h.backoff = 0
+		}
+	}
+
+	s.unregister(host, h, maxConns)
+}
+
+// unregister drops the limit of a request that is done with the host and wakes up the requests waiting
+// for it, since the host's limit may have gone up. The caller holds s.mu.
+func (s *hostScheduler) unregister(host string, h *hostState, maxConns int) {
+	if h.limits[maxConns]--; h.limits[maxConns] == 0 {
+		delete(h.limits, maxConns)
+	}
+
+	close(h.changed)
+	h.changed = make(chan struct{})
+
+	// Forget idle hosts once their delay has passed
+	if len(h.limits) == 0 && h.backoff == 0 && time.Now().After(h.next) {
+		delete(s.hosts, host)
+	}
+}
+
+// limit returns the lowest connection limit among the requests using the host
+func (h *hostState) limit() int {
+	lowest := 0
+	for maxConns := range h.limits {
+		if lowest == 0 || maxConns < lowest {
+			lowest = maxConns
+		}
+	}
+	return lowest
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return time.Until(at)
+	}
+	return 0
+}
+
+// hostDelay returns the delay between requests to the URL's host: the job's RequestDelay or robots.txt Crawl-delay, whichever is longer
+func (w *Worker) hostDelay(u *url.URL) time.Duration {
+	delay := w.Config.RequestDelay
+	if w.Config.Robots != RobotsIgnore {
+		if crawlDelay := min(w.robotsFor(u).crawlDelay, maxCrawlDelay); crawlDelay > delay {
+			delay = crawlDelay
+		}
+	}
+	return delay
+}
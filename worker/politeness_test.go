@@ -0,0 +1,187 @@
+package worker
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// hostLimits returns the registered limits of a host's requests, with their counts
+func hostLimits(s *hostScheduler, host string) map[int]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	limits := make(map[int]int)
+	if h, exists := s.hosts[host]; exists {
+		for limit, count := range h.limits {
+			limits[limit] = count
+		}
+	}
+	return limits
+}
+
+// waitFor polls a condition until it holds or the test times out
+func waitFor(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestHostSchedulerLowestLimit(t *testing.T) {
+	const host = "example.com"
+	s := newHostScheduler()
+	ctx := context.Background()
+
+	var (
+		mu         sync.Mutex
+		inFlight   int
+		strictLeft = 5 // Requests of the job limited to 1 connection that haven't released their slot
+	)
+	request := func(limit int, release func(*http.Response)) {
+		mu.Lock()
+		inFlight++
+		if strictLeft > 0 && inFlight > 1 {
+			t.Errorf("%d requests in flight while a job limited to 1 connection uses the host", inFlight)
+		}
+		mu.Unlock()
+
+		time.Sleep(2 * time.Millisecond)
+
+		mu.Lock()
+		inFlight--
+		if limit == 1 {
+			strictLeft--
+		}
+		mu.Unlock()
+		release(nil)
+	}
+
+	// The strict job holds the only slot until every request is waiting
+	first, err := s.acquire(ctx, host, 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	inFlight++
+	mu.Unlock()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		limit := 3
+		if i < 4 {
+			limit = 1
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := s.acquire(ctx, host, 0, limit)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			request(limit, release)
+		}()
+	}
+	waitFor(t, "every request to wait", func() bool {
+		limits := hostLimits(s, host)
+		return limits[1] == 5 && limits[3] == 6
+	})
+
+	mu.Lock()
+	inFlight--
+	strictLeft--
+	mu.Unlock()
+	first(nil)
+	wg.Wait()
+
+	if limits := hostLimits(s, host); len(limits) != 0 {
+		t.Errorf("limits %v left registered after every request released its slot", limits)
+	}
+
+	// Once the strict job is done, the lenient one gets its own limit
+	var releases []func(*http.Response)
+	for i := 0; i < 3; i++ {
+		ctx, cancel := context.WithTimeout(ctx, time.Second)
+		release, err := s.acquire(ctx, host, 0, 3)
+		cancel()
+		if err != nil {
+			t.Fatalf("request %d of a job limited to 3 connections: %v", i+1, err)
+		}
+		releases = append(releases, release)
+	}
+	for _, release := range releases {
+		release(nil)
+	}
+}
+
+func TestHostSchedulerDelay(t *testing.T) {
+	const host = "example.com"
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		resp *http.Response
+		wait time.Duration
+	}{
+		{"request delay", &http.Response{StatusCode: http.StatusOK}, 50 * time.Millisecond},
+		{"retry after", &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": {"1"}}}, time.Second},
+		{"backoff without retry after", &http.Response{StatusCode: http.StatusServiceUnavailable}, minBackoff},
+	}
+	for _, test := range tests {
+		s := newHostScheduler()
+		release, err := s.acquire(ctx, host, 50*time.Millisecond, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		release(test.resp)
+
+		started := time.Now()
+		release, err = s.acquire(ctx, host, 0, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if waited := time.Since(started); waited < test.wait-10*time.Millisecond {
+			t.Errorf("%s: next request started after %s, want at least %s", test.name, waited, test.wait)
+		}
+		release(&http.Response{StatusCode: http.StatusOK})
+	}
+}
+
+func TestHostSchedulerCanceledAcquire(t *testing.T) {
+	const host = "example.com"
+	s := newHostScheduler()
+
+	held, err := s.acquire(context.Background(), host, 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := s.acquire(ctx, host, 0, 1); err == nil {
+		t.Fatal("acquire succeeded while the only slot was held")
+	}
+	held(nil)
+
+	// The canceled request neither holds a slot nor keeps its limit
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	release, err := s.acquire(ctx, host, 0, 1)
+	if err != nil {
+		t.Fatalf("acquire after a canceled one: %v", err)
+	}
+	release(nil)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if h, exists := s.hosts[host]; exists {
+		t.Errorf("host state %+v kept after every request released its slot", h)
+	}
+}
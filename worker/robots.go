@@ -0,0 +1,286 @@
+package worker
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RobotsPolicy controls how a job treats robots.txt
+type RobotsPolicy string
+
+const (
+	RobotsObey       RobotsPolicy = "obey"        // Skip disallowed URLs (default)
+	RobotsIgnore     RobotsPolicy = "ignore"      // Never fetch robots.txt
+	RobotsReportOnly RobotsPolicy = "report-only" // Crawl everything but flag disallowed pages
+)
+
+// maxRobotsSize caps how much of a robots.txt file is parsed (RFC 9309 requires at least 500 KiB)
+const maxRobotsSize = 512 * 1024
+
+// Valid reports whether the policy is one of the supported values (empty means obey)
+func (p RobotsPolicy) Valid() bool {
+	switch p {
+	case "", RobotsObey, RobotsIgnore, RobotsReportOnly:
+		return true
+	}
+	return false
+}
+
+// robotsRule is a single Allow or Disallow line
+type robotsRule struct {
+	allow   bool
+	pattern string
+}
+
+// String renders the rule as it appeared in robots.txt
+func (r robotsRule) String() string {
+	if r.allow {
+		return "Allow: " + r.pattern
+	}
+	return "Disallow: " + r.pattern
+}
+
+// robotsRules is the parsed robots.txt group that applies to our user agent
+type robotsRules struct {
+	rules      []robotsRule
+	crawlDelay time.Duration
+	sitemaps   []string
+	disallowed bool // The whole host is off limits (robots.txt unreachable)
+}
+
+// allowed reports whether the path (including query) may be fetched, and the rule that decided it
+func (r *robotsRules) allowed(path string) (bool, string) {
+	if r == nil || path == "/robots.txt" {
+		return true, ""
+	}
+	if r.disallowed {
+		return false, "robots.txt unreachable"
+	}
+
+	// The longest matching pattern wins; Allow wins ties
+	var match *robotsRule
+	for i := range r.rules {
+		rule := &r.rules[i]
+		if !robotsMatch(rule.pattern, path) {
+			continue
+		}
+		if match == nil || len(rule.pattern) > len(match.pattern) ||
+			(len(rule.pattern) == len(match.pattern) && rule.allow && !match.allow) {
+			match = rule
+		}
+	}
+
+	if match == nil || match.allow {
+		return true, ""
+	}
+	return false, match.String()
+}
+
+// robotsMatch matches a path against a robots.txt pattern supporting `*` wildcards and a trailing `$` anchor
+func robotsMatch(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	pos := len(parts[0])
+
+	for i, part := range parts[1:] {
+		last := i == len(parts)-2
+		if last && anchored {
+			// The final segment must sit at the very end of the path
+			return strings.HasSuffix(path[pos:], part)
+		}
+		idx := strings.Index(path[pos:], part)
+		if idx < 0 {
+			return false
+		}
+		pos += idx + len(part)
+	}
+
+	return !anchored || pos == len(path)
+}
+
+// robotsAgent extracts the product token robots.txt groups are matched against (e.g. "prorobot" from "ProRobot/1.0")
+func robotsAgent(userAgent string) string {
+	token := strings.TrimSpace(userAgent)
+	if i := strings.IndexFunc(token, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r == '-' || r == '_')
+	}); i >= 0 {
+		token = token[:i]
+	}
+	return strings.ToLower(token)
+}
+
+// parseRobots reads robots.txt and keeps the rules of the groups matching userAgent, falling back to `*`
+func parseRobots(body io.Reader, userAgent string) *robotsRules {
+	agent := robotsAgent(userAgent)
+
+	type group struct {
+		agents     []string
+		rules      []robotsRule
+		crawlDelay time.Duration
+	}
+
+	var (
+		groups   []*group
+		current  *group
+		sitemaps []string
+	)
+
+	scanner := bufio.NewScanner(io.LimitReader(body, maxRobotsSize))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			// Consecutive user-agent lines share one group
+			if current == nil || len(current.rules) > 0 || current.crawlDelay > 0 {
+				current = &group{}
+				groups = append(groups, current)
+			}
+			current.agents = append(current.agents, strings.ToLower(value))
+		case "allow", "disallow":
+			if current == nil || value == "" {
+				continue
+			}
+			current.rules = append(current.rules, robotsRule{allow: key == "allow", pattern: value})
+		case "crawl-delay":
+			if current == nil {
+				continue
+			}
+			if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+				current.crawlDelay = time.Duration(seconds * float64(time.Second))
+			}
+		case "sitemap":
+			sitemaps = append(sitemaps, value)
+		}
+	}
+
+	// Merge every group naming our agent; only use `*` if none do
+	pick := func(match func(string) bool) *robotsRules {
+		var rules *robotsRules
+		for _, g := range groups {
+			for _, a := range g.agents {
+				if !match(a) {
+					continue
+				}
+				if rules == nil {
+					rules = &robotsRules{}
+				}
+				rules.rules = append(rules.rules, g.rules...)
+				if g.crawlDelay > rules.crawlDelay {
+					rules.crawlDelay = g.crawlDelay
+				}
+				break
+			}
+		}
+		return rules
+	}
+
+	rules := pick(func(a string) bool { return agent != "" && robotsAgent(a) == agent })
+	if rules == nil {
+		rules = pick(func(a string) bool { return a == "*" })
+	}
+	if rules == nil {
+		rules = &robotsRules{}
+	}
+	rules.sitemaps = sitemaps
+	return rules
+}
+
+// robotsCache fetches robots.txt at most once per host for the lifetime of a job
+type robotsCache struct {
+	mu      sync.Mutex
+	entries map[string]*robotsEntry
+}
+
+type robotsEntry struct {
+	once  sync.Once
+	rules *robotsRules
+}
+
+// robotsFor returns the robots.txt rules for the host of u
+func (w *Worker) robotsFor(u *url.URL) *robotsRules {
+	key := u.Scheme + "://" + u.Host
+
+	w.robots.mu.Lock()
+	if w.robots.entries == nil {
+		w.robots.entries = make(map[string]*robotsEntry)
+	}
+	entry, exists := w.robots.entries[key]
+	if !exists {
+		entry = &robotsEntry{}
+		w.robots.entries[key] = entry
+	}
+	w.robots.mu.Unlock()
+
+	entry.once.Do(func() {
+		entry.rules = w.fetchRobots(key)
+	})
+	return entry.rules
+}
+
+// fetchRobots downloads and parses robots.txt following RFC 9309 error handling
+func (w *Worker) fetchRobots(origin string) *robotsRules {
+	req, err := w.newRequest("GET", origin+"/robots.txt")
+	if err != nil {
+		return &robotsRules{}
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		log.Printf("⚠️ Failed to fetch robots.txt for %s: %v", origin, err)
+		return &robotsRules{disallowed: true}
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return parseRobots(resp.Body, w.Config.CustomHeaders["User-Agent"])
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		// No robots.txt means everything is allowed
+		return &robotsRules{}
+	default:
+		log.Printf("⚠️ robots.txt for %s returned %d, treating host as disallowed", origin, resp.StatusCode)
+		return &robotsRules{disallowed: true}
+	}
+}
+
+// checkRobots applies the job's robots policy; it returns false if the URL must be skipped
+func (w *Worker) checkRobots(urlStr string, u *url.URL, metadata map[string]interface{}) bool {
+	if w.Config.Robots == RobotsIgnore {
+		return true
+	}
+
+	allowed, rule := w.robotsFor(u).allowed(u.RequestURI())
+	if allowed {
+		return true
+	}
+
+	if w.Config.Robots == RobotsReportOnly {
+		metadata["robots_disallowed"] = rule
+		return true
+	}
+
+	w.skip(urlStr, &FetchError{Class: ErrorRobotsBlocked, Err: errors.New(rule)})
+	return false
+}
@@ -0,0 +1,97 @@
+package worker
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRobotsMatch(t *testing.T) {
+	tests := []struct {
+		pattern, path string
+		want          bool
+	}{
+		{"/", "/anything", true},
+		{"/private", "/private/page", true},
+		{"/private", "/public", false},
+		{"/*.pdf", "/docs/file.pdf", true},
+		{"/*.pdf", "/docs/file.pdf?download=1", true},
+		{"/*.pdf$", "/docs/file.pdf?download=1", false},
+		{"/*.pdf$", "/docs/file.pdf", true},
+		{"/page$", "/page", true},
+		{"/page$", "/page/more", false},
+		{"/a*b*c", "/axxbyyc", true},
+		{"/a*b*c", "/axxcyyb", false},
+		{"/search?q=*", "/search?q=robots", true},
+		{"*/admin", "/site/admin", true},
+	}
+	for _, test := range tests {
+		if got := robotsMatch(test.pattern, test.path); got != test.want {
+			t.Errorf("robotsMatch(%q, %q) = %v, want %v", test.pattern, test.path, got, test.want)
+		}
+	}
+}
+
+func TestParseRobots(t *testing.T) {
+	body := `# Example
+User-agent: *
+Disallow: /private
+Crawl-delay: 1
+
+User-agent: ProRobot
+User-agent: OtherBot
+Disallow: /
+Allow: /public
+Allow: /shop
+Disallow: /shop
+Crawl-delay: 2.5
+
+User-agent: prorobot
+Disallow: /drafts # merged with the group above
+
+Sitemap: https://example.com/sitemap.xml
+`
+
+	tests := []struct {
+		name      string
+		userAgent string
+		path      string
+		allowed   bool
+		rule      string
+	}{
+		{"named group", "ProRobot/1.0", "/about", false, "Disallow: /"},
+		{"longest match wins", "ProRobot/1.0", "/public/page", true, ""},
+		{"allow wins ties", "ProRobot/1.0", "/shop", true, ""},
+		{"merged groups", "ProRobot/1.0", "/drafts/post", false, "Disallow: /drafts"},
+		{"robots.txt itself", "ProRobot/1.0", "/robots.txt", true, ""},
+		{"fallback to star", "SomeBot/2.0", "/private/page", false, "Disallow: /private"},
+		{"star allows the rest", "SomeBot/2.0", "/about", true, ""},
+		{"no user agent", "", "/private", false, "Disallow: /private"},
+	}
+	for _, test := range tests {
+		rules := parseRobots(strings.NewReader(body), test.userAgent)
+		allowed, rule := rules.allowed(test.path)
+		if allowed != test.allowed || rule != test.rule {
+			t.Errorf("%s: allowed(%q) = %v, %q, want %v, %q", test.name, test.path, allowed, rule, test.allowed, test.rule)
+		}
+	}
+
+	rules := parseRobots(strings.NewReader(body), "ProRobot/1.0")
+	if rules.crawlDelay != 2500*time.Millisecond {
+		t.Errorf("crawlDelay = %s, want 2.5s", rules.crawlDelay)
+	}
+	if want := []string{"https://example.com/sitemap.xml"}; !reflect.DeepEqual(rules.sitemaps, want) {
+		t.Errorf("sitemaps = %v, want %v", rules.sitemaps, want)
+	}
+	if rules := parseRobots(strings.NewReader(body), "SomeBot"); rules.crawlDelay != time.Second {
+		t.Errorf("crawlDelay for * = %s, want 1s", rules.crawlDelay)
+	}
+}
+
+func TestRobotsUnreachable(t *testing.T) {
+	rules := &robotsRules{disallowed: true}
+	if allowed, rule := rules.allowed("/"); allowed || rule != "robots.txt unreachable" {
+		t.Errorf("allowed(/) = %v, %q, want false, robots.txt unreachable", allowed, rule)
+	}
+}
@@ -0,0 +1,224 @@
+package worker
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// ScopeMode selects which hosts and paths a job may crawl
+type ScopeMode string
+
+const (
+	ScopeHost       ScopeMode = "host"        // Exactly the start URL's host (default)
+	ScopeDomain     ScopeMode = "domain"      // The registrable domain and all of its subdomains
+	ScopePathPrefix ScopeMode = "path_prefix" // The start URL's host, below a path prefix
+)
+
+// ScopeRule includes or excludes URLs matching a pattern.
+// Patterns starting with "/" match the path and query, anything else matches the full URL.
+type ScopeRule struct {
+	Action  string `json:"action"`  // include or exclude
+	Pattern string `json:"pattern"` // Glob ("*" within a segment, "**" across segments) or regular expression
+	Regex   bool   `json:"regex"`   // Treat Pattern as a regular expression
+}
+
+// ScopeConfig is the job-level scope configuration
+type ScopeConfig struct {
+	Mode       ScopeMode   `json:"mode"`
+	PathPrefix string      `json:"path_prefix"` // For path_prefix mode, defaults to the start URL's directory
+	Rules      []ScopeRule `json:"rules"`       // Evaluated in order, the first match decides
+}
+
+// EffectiveScope describes the scope a running job applies, for auditing skipped URLs
+type EffectiveScope struct {
+	Mode       ScopeMode      `json:"mode"`
+	Host       string         `json:"host,omitempty"`
+	Domain     string         `json:"domain,omitempty"`
+	PathPrefix string         `json:"path_prefix,omitempty"`
+	Rules      []ScopeRule    `json:"rules,omitempty"`
+	Rejected   map[string]int `json:"rejected,omitempty"` // Out-of-scope URLs seen, by reason
+}
+
+// scope is a compiled ScopeConfig
+type scope struct {
+	EffectiveScope
+	start    string // Normalized start URL, always in scope
+	patterns []*regexp.Regexp
+	includes bool // At least one include rule exists, so unmatched URLs are rejected
+
+	mu sync.Mutex
+}
+
+// newScope compiles a scope configuration for a normalized start URL
+func newScope(config ScopeConfig, start *url.URL) (*scope, error) {
+	s := &scope{start: start.String()}
+	s.Mode = config.Mode
+	if s.Mode == "" {
+		s.Mode = ScopeHost
+	}
+
+	switch s.Mode {
+	case ScopeHost:
+		s.Host = start.Host
+	case ScopeDomain:
+		domain, err := publicsuffix.EffectiveTLDPlusOne(start.Hostname())
+		if err != nil {
+			// IP addresses and bare suffixes have no registrable domain
+			domain = start.Hostname()
+		}
+		s.Domain = domain
+	case ScopePathPrefix:
+		s.Host = start.Host
+		s.PathPrefix = config.PathPrefix
+		if s.PathPrefix == "" {
+			s.PathPrefix = start.Path[:strings.LastIndex(start.Path, "/")+1]
+		}
+		if s.PathPrefix == "" {
+			s.PathPrefix = "/" // Bare host start URLs have an empty path
+		}
+		if !strings.HasPrefix(s.PathPrefix, "/") {
+			return nil, fmt.Errorf("path_prefix %q must start with /", s.PathPrefix)
+		}
+	default:
+		return nil, fmt.Errorf("unknown scope mode %q", config.Mode)
+	}
+
+	for i, rule := range config.Rules {
+		if rule.Action != "include" && rule.Action != "exclude" {
+			return nil, fmt.Errorf("scope rule %d: action must be include or exclude", i+1)
+		}
+		expr := rule.Pattern
+		if !rule.Regex {
+			expr = globToRegexp(rule.Pattern)
+		}
+		pattern, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("scope rule %d: %v", i+1, err)
+		}
+		s.patterns = append(s.patterns, pattern)
+		s.includes = s.includes || rule.Action == "include"
+	}
+	s.Rules = config.Rules
+
+	return s, nil
+}
+
+// ValidateScope checks a scope configuration before a job is created
+func ValidateScope(config ScopeConfig, startURL string) error {
+	start, err := url.Parse(startURL)
+	if err != nil {
+		return err
+	}
+	_, err = newScope(config, start)
+	return err
+}
+
+// allows reports whether a normalized URL is in scope, counting rejections by reason
+func (s *scope) allows(u *url.URL) bool {
+	reason := s.reject(u)
+	if reason == "" {
+		return true
+	}
+
+	s.mu.Lock()
+	if s.Rejected == nil {
+		s.Rejected = make(map[string]int)
+	}
+	s.Rejected[reason]++
+	s.mu.Unlock()
+	return false
+}
+
+// reject returns why a URL is out of scope, or "" if it is in scope
+func (s *scope) reject(u *url.URL) string {
+	if u.String() == s.start {
+		return ""
+	}
+
+	switch s.Mode {
+	case ScopeHost:
+		if u.Host != s.Host {
+			return "external host"
+		}
+	case ScopeDomain:
+		host := u.Hostname()
+		if host != s.Domain && !strings.HasSuffix(host, "."+s.Domain) {
+			return "external domain"
+		}
+	case ScopePathPrefix:
+		if u.Host != s.Host {
+			return "external host"
+		}
+		if !strings.HasPrefix(u.Path, s.PathPrefix) {
+			return "outside path prefix"
+		}
+	}
+
+	for i, rule := range s.Rules {
+		subject := u.String()
+		if strings.HasPrefix(rule.Pattern, "/") {
+			subject = u.RequestURI()
+		}
+		if s.patterns[i].MatchString(subject) {
+			if rule.Action == "exclude" {
+				return fmt.Sprintf("excluded by rule %d (%s)", i+1, rule.Pattern)
+			}
+			return ""
+		}
+	}
+
+	if s.includes {
+		return "matched no include rule"
+	}
+	return ""
+}
+
+// internal reports whether a URL is on the crawled site, ignoring path prefixes and rules
+func (s *scope) internal(u *url.URL) bool {
+	if s.Mode == ScopeDomain {
+		host := u.Hostname()
+		return host == s.Domain || strings.HasSuffix(host, "."+s.Domain)
+	}
+	return u.Host == s.Host
+}
+
+// effective returns a snapshot of the scope for status reporting
+func (s *scope) effective() EffectiveScope {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	summary := s.EffectiveScope
+	summary.Rejected = make(map[string]int, len(s.Rejected))
+	for reason, count := range s.Rejected {
+		summary.Rejected[reason] = count
+	}
+	return summary
+}
+
+// globToRegexp translates a glob into an anchored regular expression
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				b.WriteString(".*")
+				i++
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
@@ -0,0 +1,75 @@
+package worker
+
+import (
+	"net/url"
+	"regexp"
+	"testing"
+)
+
+func TestGlobToRegexp(t *testing.T) {
+	tests := []struct {
+		glob, subject string
+		want          bool
+	}{
+		{"/docs/*", "/docs/intro", true},
+		{"/docs/*", "/docs/guide/intro", false},
+		{"/docs/**", "/docs/guide/intro", true},
+		{"/docs/**", "/docs/", true},
+		{"/docs/**", "/blog/docs/a", false},
+		{"/**.pdf", "/files/2026/report.pdf", true},
+		{"/**.pdf", "/files/report.pdfx", false},
+		{"/page?", "/page1", true},
+		{"/page?", "/page/", false},
+		{"/page?", "/page", false},
+		{"/search?q=*", "/search?q=robots", true}, // "?" matches any character, itself included
+		{"/a.b", "/a.b", true},
+		{"/a.b", "/axb", false},
+		{"/(x)+", "/(x)+", true},
+		{"https://*.example.com/**", "https://blog.example.com/post/1", true},
+		{"https://*.example.com/**", "https://a.b.example.com/", true}, // Dots don't delimit segments
+		{"https://*.example.com/**", "https://example.com/", false},
+	}
+	for _, test := range tests {
+		pattern, err := regexp.Compile(globToRegexp(test.glob))
+		if err != nil {
+			t.Fatalf("globToRegexp(%q) = %q does not compile: %v", test.glob, globToRegexp(test.glob), err)
+		}
+		if got := pattern.MatchString(test.subject); got != test.want {
+			t.Errorf("glob %q matching %q = %v, want %v", test.glob, test.subject, got, test.want)
+		}
+	}
+}
+
+func TestScopeReject(t *testing.T) {
+	tests := []struct {
+		name   string
+		start  string
+		config ScopeConfig
+		url    string
+		want   string
+	}{
+		{"same host", "https://example.com/", ScopeConfig{}, "https://example.com/about", ""},
+		{"other host", "https://example.com/", ScopeConfig{}, "https://blog.example.com/", "external host"},
+		{"subdomain in domain mode", "https://www.example.co.uk/", ScopeConfig{Mode: ScopeDomain}, "https://blog.example.co.uk/", ""},
+		{"other domain", "https://www.example.co.uk/", ScopeConfig{Mode: ScopeDomain}, "https://example.com/", "external domain"},
+		{"below prefix", "https://example.com/docs/intro", ScopeConfig{Mode: ScopePathPrefix}, "https://example.com/docs/guide", ""},
+		{"outside prefix", "https://example.com/docs/intro", ScopeConfig{Mode: ScopePathPrefix}, "https://example.com/blog", "outside path prefix"},
+		{"bare host prefix", "https://example.com", ScopeConfig{Mode: ScopePathPrefix}, "https://example.com/blog", ""},
+		{"excluded", "https://example.com/", ScopeConfig{Rules: []ScopeRule{{Action: "exclude", Pattern: "/private/**"}}}, "https://example.com/private/a", "excluded by rule 1 (/private/**)"},
+		{"first rule wins", "https://example.com/", ScopeConfig{Rules: []ScopeRule{{Action: "include", Pattern: "/private/public"}, {Action: "exclude", Pattern: "/private/**"}}}, "https://example.com/private/public", ""},
+		{"no include matched", "https://example.com/", ScopeConfig{Rules: []ScopeRule{{Action: "include", Pattern: "/docs/**"}}}, "https://example.com/blog", "matched no include rule"},
+		{"start always allowed", "https://example.com/", ScopeConfig{Rules: []ScopeRule{{Action: "include", Pattern: "/docs/**"}}}, "https://example.com/", ""},
+		{"regex rule", "https://example.com/", ScopeConfig{Rules: []ScopeRule{{Action: "exclude", Pattern: `\?page=\d+`, Regex: true}}}, "https://example.com/list?page=2", `excluded by rule 1 (\?page=\d+)`},
+	}
+	for _, test := range tests {
+		start, _ := url.Parse(test.start)
+		s, err := newScope(test.config, normalizeURL(start, NormalizeConfig{}))
+		if err != nil {
+			t.Fatalf("%s: newScope: %v", test.name, err)
+		}
+		u, _ := url.Parse(test.url)
+		if got := s.reject(normalizeURL(u, NormalizeConfig{})); got != test.want {
+			t.Errorf("%s: reject(%q) = %q, want %q", test.name, test.url, got, test.want)
+		}
+	}
+}
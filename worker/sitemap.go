@@ -0,0 +1,191 @@
+package worker
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	maxSitemapSize    = 50 * 1024 * 1024 // Uncompressed size limit from the sitemap protocol
+	maxSitemapNesting = 3                // How deep sitemap index files may point to other indexes
+	maxSitemapURLs    = 50000            // URLs seeded into the frontier per job
+)
+
+// sitemapDocument covers both <urlset> and <sitemapindex> files
+type sitemapDocument struct {
+	XMLName  xml.Name
+	URLs     []sitemapEntry `xml:"url"`
+	Sitemaps []sitemapEntry `xml:"sitemap"`
+}
+
+// sitemapEntry is a <url> or <sitemap> element
+type sitemapEntry struct {
+	Loc      string `xml:"loc"`
+	LastMod  string `xml:"lastmod"`
+	Priority string `xml:"priority"`
+}
+
+// sitemapTimeFormats are the W3C datetime variants allowed in <lastmod>
+var sitemapTimeFormats = []string{
+	time.RFC3339,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+	"2006-01",
+	"2006",
+}
+
+// seedSitemaps adds every URL listed in the site's sitemaps to the frontier
+func (w *Worker) seedSitemaps() {
+	seen := make(map[string]bool)
+	count := 0
+
+	for _, sitemapURL := range w.sitemapLocations() {
+		w.readSitemap(sitemapURL, 0, seen, &count)
+	}
+
+	log.Printf("🗺️ Job %d seeded %d URLs from sitemaps", w.JobID, count)
+	if w.StatusCb != nil {
+		w.StatusCb(w.JobID, fmt.Sprintf("Seeded %d URLs from sitemaps", count))
+	}
+}
+
+// sitemapLocations lists the sitemaps to read: the start URL if it is one, robots.txt `Sitemap:` lines, then /sitemap.xml
+func (w *Worker) sitemapLocations() []string {
+	start, err := url.Parse(w.StartURL)
+	if err != nil {
+		return nil
+	}
+
+	if isSitemapURL(w.StartURL) {
+		return []string{start.String()}
+	}
+
+	var locations []string
+	if w.Config.Robots != RobotsIgnore {
+		locations = append(locations, w.robotsFor(start).sitemaps...)
+	}
+	return append(locations, start.Scheme+"://"+start.Host+"/sitemap.xml")
+}
+
+// isSitemapURL reports whether the URL points at an XML sitemap rather than a page
+func isSitemapURL(urlStr string) bool {
+	parsedURL, err := url.Parse(urlStr)
+	if err != nil {
+		return false
+	}
+	path := strings.ToLower(parsedURL.Path)
+	return strings.HasSuffix(path, ".xml") || strings.HasSuffix(path, ".xml.gz")
+}
+
+// readSitemap fetches a sitemap or sitemap index and enqueues its URLs
+func (w *Worker) readSitemap(sitemapURL string, nesting int, seen map[string]bool, count *int) {
+	if seen[sitemapURL] || nesting > maxSitemapNesting || *count >= maxSitemapURLs {
+		return
+	}
+	seen[sitemapURL] = true
+
+	doc, err := w.fetchSitemap(sitemapURL)
+	if err != nil {
+		log.Printf("⚠️ Failed to read sitemap %s: %v", sitemapURL, err)
+		return
+	}
+
+	for _, entry := range doc.Sitemaps {
+		w.readSitemap(strings.TrimSpace(entry.Loc), nesting+1, seen, count)
+	}
+
+	var items []frontierItem
+	for _, entry := range doc.URLs {
+		if *count+len(items) >= maxSitemapURLs {
+			break
+		}
+		loc := strings.TrimSpace(entry.Loc)
+		if loc == "" {
+			continue
+		}
+		items = append(items, frontierItem{
+			URL:      loc,
+			Priority: parseSitemapPriority(entry.Priority),
+			LastMod:  parseSitemapTime(entry.LastMod),
+			Source:   SourceSitemap,
+		})
+	}
+	*count += w.enqueue(w.base, items...)
+}
+
+// fetchSitemap downloads and decodes a sitemap, transparently handling gzip
+func (w *Worker) fetchSitemap(sitemapURL string) (*sitemapDocument, error) {
+	parsedURL, err := url.Parse(sitemapURL)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := w.newRequest("GET", sitemapURL)
+	if err != nil {
+		return nil, err
+	}
+
+	release, err := politeness.acquire(w.ctx, parsedURL.Host, w.hostDelay(parsedURL), w.Config.MaxConnsPerHost)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		release(nil)
+		return nil, err
+	}
+	defer resp.Body.Close()
+	defer release(resp)
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	// Sniff the gzip magic bytes rather than trusting the extension or Content-Type
+	body := bufio.NewReader(resp.Body)
+	var reader io.Reader = body
+	if magic, err := body.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		reader = gz
+	}
+
+	var doc sitemapDocument
+	if err := xml.NewDecoder(io.LimitReader(reader, maxSitemapSize)).Decode(&doc); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// parseSitemapPriority reads <priority>, defaulting to 0.5 as the protocol specifies
+func parseSitemapPriority(value string) float64 {
+	priority, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || priority < 0 || priority > 1 {
+		return defaultPriority
+	}
+	return priority
+}
+
+// parseSitemapTime reads <lastmod>, returning the zero time if it is missing or malformed
+func parseSitemapTime(value string) time.Time {
+	value = strings.TrimSpace(value)
+	for _, format := range sitemapTimeFormats {
+		if t, err := time.Parse(format, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
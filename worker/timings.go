@@ -0,0 +1,92 @@
+package worker
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// fetchTimings records when the phases of a GET happened. Redirects reuse the request's context,
+// so the connection phases are those of the final hop while the total spans every hop.
+type fetchTimings struct {
+	mu           sync.Mutex // Trace hooks may run on the transport's goroutines
+	start        time.Time  // First connection requested, after the politeness wait
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	firstByte    time.Time
+	done         time.Time // Body read
+}
+
+// timingsKey is the context key of a request's fetchTimings
+type timingsKey struct{}
+
+// traceTimings returns a copy of req that records its fetchTimings
+func traceTimings(req *http.Request) (*http.Request, *fetchTimings) {
+	timings := &fetchTimings{}
+	trace := &httptrace.ClientTrace{
+		GetConn: func(string) {
+			timings.mu.Lock()
+			if timings.start.IsZero() {
+				timings.start = time.Now()
+			}
+			timings.mu.Unlock()
+		},
+		DNSStart:             func(httptrace.DNSStartInfo) { timings.mark(&timings.dnsStart) },
+		DNSDone:              func(httptrace.DNSDoneInfo) { timings.mark(&timings.dnsDone) },
+		ConnectStart:         func(string, string) { timings.mark(&timings.connectStart) },
+		ConnectDone:          func(string, string, error) { timings.mark(&timings.connectDone) },
+		TLSHandshakeStart:    func() { timings.mark(&timings.tlsStart) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { timings.mark(&timings.tlsDone) },
+		GotFirstResponseByte: func() { timings.mark(&timings.firstByte) },
+	}
+	ctx := context.WithValue(httptrace.WithClientTrace(req.Context(), trace), timingsKey{}, timings)
+	return req.WithContext(ctx), timings
+}
+
+// responseTimings returns the fetchTimings recorded for a response, or nil if its request wasn't traced
+func responseTimings(resp *http.Response) *fetchTimings {
+	if resp == nil || resp.Request == nil {
+		return nil
+	}
+	timings, _ := resp.Request.Context().Value(timingsKey{}).(*fetchTimings)
+	return timings
+}
+
+// mark records the current time for a phase
+func (t *fetchTimings) mark(phase *time.Time) {
+	t.mu.Lock()
+	*phase = time.Now()
+	t.mu.Unlock()
+}
+
+// finish records that the body has been read
+func (t *fetchTimings) finish() {
+	t.mark(&t.done)
+}
+
+// metadata returns the phase durations in milliseconds, leaving out the phases a reused connection skipped
+func (t *fetchTimings) metadata() map[string]interface{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	timings := make(map[string]interface{})
+	add := func(key string, from, to time.Time) {
+		if !from.IsZero() && !to.IsZero() && !to.Before(from) {
+			timings[key] = float64(to.Sub(from).Microseconds()) / 1000
+		}
+	}
+	add("dns_ms", t.dnsStart, t.dnsDone)
+	add("connect_ms", t.connectStart, t.connectDone)
+	add("tls_ms", t.tlsStart, t.tlsDone)
+	add("ttfb_ms", t.start, t.firstByte)
+	add("download_ms", t.firstByte, t.done)
+	add("total_ms", t.start, t.done)
+	return timings
+}
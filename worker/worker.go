@@ -1,6 +1,8 @@
 package worker
 
 import (
+	"context"
+	"errors"
 	"log"
 	"net/http"
 	"net/url"
@@ -18,23 +20,103 @@ type WorkerResult struct {
 	URL     string
 	Title   string
 	Content string
+	Depth   int
 }
 
 // WorkerConfig allows custom configuration for the worker.
 type WorkerConfig struct {
-	MaxLinks      int               // Maximum number of links to crawl
-	RequestDelay  time.Duration     // Delay between requests
-	CustomHeaders map[string]string // Optional HTTP headers for requests
+	MaxLinks        int               // Maximum number of links to crawl
+	MaxDepth        int               // Maximum hop distance from the start URL (0 = unlimited)
+	Concurrency     int               // Number of fetcher goroutines (default 4)
+	Robots          RobotsPolicy      // How robots.txt is applied (default obey)
+	RequestDelay    time.Duration     // Minimum delay between requests to the same host
+	MaxConnsPerHost int               // Concurrent requests per host across all jobs (default 2)
+	CustomHeaders   map[string]string // Optional HTTP headers for requests
+	MaxRetries      int               // Extra attempts for transient failures (timeouts, resets, 5xx, 429)
+	RetryBaseDelay  time.Duration     // First retry backoff, doubled per attempt (default 500ms)
+	RetryMaxDelay   time.Duration     // Backoff ceiling (default 30s)
+
+	ConnectTimeout     time.Duration // TCP connect and TLS handshake timeout (default 10s)
+	ReadTimeout        time.Duration // Time to wait for response headers (default 30s)
+	TotalTimeout       time.Duration // Whole request including the body (default 60s)
+	ProxyURL           string        // HTTP(S) proxy, defaults to the environment's proxy settings
+	CABundle           string        // Extra PEM-encoded root certificates to trust
+	InsecureSkipVerify bool          // Accept any TLS certificate
+	MaxRedirects       int           // Redirects to follow per request (0 = default 10, negative = none)
+
+	MaxBodyBytes        int64    // Largest response body read (default 10 MiB)
+	AllowedContentTypes []string // Sniffed media types that are parsed (default text/html, application/xhtml+xml)
+	HeadRequests        bool     // Send HEAD first to skip large or non-HTML resources without downloading them
+
+	Normalize   NormalizeConfig  // How URLs are canonicalized for deduplication
+	Scope       ScopeConfig      // Which hosts and paths may be crawled (default the start host)
+	Sitemaps    bool             // Seed the frontier from the site's sitemaps
+	SitemapOnly bool             // Only crawl sitemap URLs, never follow links (implies Sitemaps)
+	Incremental bool             // Revisit the previous crawl's pages with conditional requests
+	ContentMode ContentMode      // What is stored as page text (default main)
+	Extraction  ExtractionSchema // Record extracted from each page with CSS selectors (none by default)
+
+	CheckExternalLinks bool // Request external link targets once the crawl is done, without crawling them
+}
+
+// defaultConcurrency is used when WorkerConfig.Concurrency is not set
+const defaultConcurrency = 4
+
+// WorkerProgress is a snapshot of the crawl frontier.
+type WorkerProgress struct {
+	Queued    int // URLs waiting in the frontier
+	InFlight  int // URLs currently being fetched
+	Done      int // URLs fully processed
+	Processed int // URLs claimed against MaxLinks
+	Total     int // MaxLinks
 }
 
 // WorkerStatusCallback defines a function signature for status reporting.
 type WorkerStatusCallback func(jobID uint64, message string)
 
-// Cancel stops the worker immediately
+// Cancel stops the worker immediately, aborting in-flight requests, and marks the job canceled
 func (w *Worker) Cancel() {
 	w.mu.Lock()
 	w.canceled = true
+	w.cond.Broadcast() // Wake idle fetchers so they can exit
+	w.mu.Unlock()
+	w.stop()
+}
+
+// Abandon stops the worker like Cancel but leaves the job row alone, for jobs that were deleted or now belong to another node
+func (w *Worker) Abandon() {
+	w.mu.Lock()
+	w.abandoned = true
+	w.cond.Broadcast()
 	w.mu.Unlock()
+	w.stop()
+}
+
+// Done returns a channel that is closed once Start has returned
+func (w *Worker) Done() <-chan struct{} {
+	return w.stopped
+}
+
+// Canceled reports whether Cancel was called
+func (w *Worker) Canceled() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.canceled
+}
+
+// Pause stops claiming new URLs; in-flight fetches finish and the persisted frontier is kept for Resume
+func (w *Worker) Pause() {
+	w.mu.Lock()
+	w.paused = true
+	w.cond.Broadcast() // Wake idle fetchers so they can exit
+	w.mu.Unlock()
+}
+
+// Paused reports whether Pause was called
+func (w *Worker) Paused() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.paused
 }
 
 // NewWorker initializes a new worker instance with custom config
@@ -44,157 +126,582 @@ func NewWorker(jobID uint64, startURL string, config WorkerConfig, cb WorkerStat
 		log.Fatalf("Invalid start URL: %v", err)
 	}
 
-	return &Worker{
-		visited:  make(map[string]bool),
-		Config:   config,
-		JobID:    jobID,
-		StartURL: startURL,
-		Results:  make([]WorkerResult, 0),
-		StatusCb: cb,
-		Host:     parsedURL.Host, // Store the base domain to filter links
+	if config.Concurrency <= 0 {
+		config.Concurrency = defaultConcurrency
+	}
+
+	client, err := NewHTTPClient(config)
+	if err != nil {
+		log.Printf("⚠️ Invalid HTTP client config for job %d, using defaults: %v", jobID, err)
+		client, _ = NewHTTPClient(WorkerConfig{MaxConnsPerHost: config.MaxConnsPerHost})
 	}
+
+	normalizedURL := normalizeURL(parsedURL, config.Normalize)
+	crawlScope, err := newScope(config.Scope, normalizedURL)
+	if err != nil {
+		log.Printf("⚠️ Invalid scope for job %d, limiting to the start host: %v", jobID, err)
+		crawlScope, _ = newScope(ScopeConfig{}, normalizedURL)
+	}
+
+	pageExtractor, err := newExtractor(config.Extraction)
+	if err != nil {
+		log.Printf("⚠️ Invalid extraction schema for job %d, not extracting records: %v", jobID, err)
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
+
+	w := &Worker{
+		ctx:       ctx,
+		stop:      stop,
+		stopped:   make(chan struct{}),
+		client:    client,
+		scope:     crawlScope,
+		extractor: pageExtractor,
+		base:      parsedURL,
+		visited:   make(map[string]bool),
+		Config:    config,
+		JobID:     jobID,
+		StartURL:  startURL,
+		Results:   make([]WorkerResult, 0),
+		StatusCb:  cb,
+		Host:      normalizedURL.Host, // Store the base domain
+	}
+	w.cond = sync.NewCond(&w.mu)
+	return w
 }
 
 // Worker struct to manage crawl state
 type Worker struct {
-	mu       sync.Mutex
-	wg       sync.WaitGroup
-	visited  map[string]bool
-	counter  int
-	Config   WorkerConfig
-	JobID    uint64
-	StartURL string
-	Results  []WorkerResult
-	StatusCb WorkerStatusCallback
-	Host     string // Base host (e.g., "example.com")
-	canceled bool
-}
-
-// Start begins the crawling process
-func (w *Worker) Start() {
+	mu        sync.Mutex
+	cond      *sync.Cond // Signaled when the frontier grows or a fetch finishes
+	wg        sync.WaitGroup
+	visited   map[string]bool
+	frontier  frontier
+	counter   int
+	inFlight  int
+	done      int
+	robots    robotsCache
+	client    *http.Client
+	base      *url.URL // Parsed start URL that seeds are resolved against
+	scope     *scope
+	extractor *extractor // Compiled Config.Extraction, nil without a schema
+	Config    WorkerConfig
+	JobID     uint64
+	StartURL  string
+	Results   []WorkerResult
+	StatusCb  WorkerStatusCallback
+	Host      string // Base host (e.g., "example.com")
+	canceled  bool
+	paused    bool
+	resumed   bool // The frontier was restored from the database
+
+	previousJob    uint64                   // Previous completed crawl of the start URL, 0 if none
+	previous       map[string]database.Page // Its pages by URL, without content; read-only once fetchers start
+	sameExtraction bool                     // It extracted records with Config.Extraction, so 304 pages can keep theirs
+
+	ctx       context.Context // Scopes every request and database write, canceled by Cancel and Abandon
+	stop      context.CancelFunc
+	stopped   chan struct{} // Closed when Start returns
+	abandoned bool
+}
+
+// Start begins the crawling process; canceling ctx cancels the job
+func (w *Worker) Start(ctx context.Context) {
+	defer close(w.stopped)
+	defer context.AfterFunc(ctx, w.Cancel)()
+
 	log.Printf("Starting crawl job %d for URL: %s", w.JobID, w.StartURL)
 	database.UpdateJobStatus(w.JobID, "in_progress")
-
-	if w.StatusCb != nil {
-		w.StatusCb(w.JobID, "Job started")
+	w.report("Job started")
+	w.loadPrevious()
+
+	if w.resumed {
+		log.Printf("🔁 Resuming job %d with %d queued URLs", w.JobID, w.frontier.Len())
+	} else {
+		// Sitemaps are read up front so their priorities order the whole frontier.
+		// A sitemap start URL can't be crawled as a page, so it implies Sitemaps.
+		if w.Config.Sitemaps || w.Config.SitemapOnly || isSitemapURL(w.StartURL) {
+			w.seedSitemaps()
+		}
+		if !w.Config.SitemapOnly && !isSitemapURL(w.StartURL) {
+			w.enqueue(w.base, frontierItem{URL: w.StartURL, Priority: seedPriority, Source: SourceSeed})
+		}
+		if w.Config.Incremental && len(w.previous) > 0 {
+			w.seedPrevious()
+		}
 	}
 
-	w.wg.Add(1)
-	go w.crawl(w.StartURL)
+	// A fixed pool of fetchers drains the frontier, shallowest URLs first
+	for i := 0; i < w.Config.Concurrency; i++ {
+		w.wg.Add(1)
+		go w.fetcher()
+	}
 	w.wg.Wait()
-
-	if w.StatusCb != nil {
-		w.StatusCb(w.JobID, "Job completed")
+	if w.Config.CheckExternalLinks && w.ctx.Err() == nil && !w.Paused() {
+		w.checkExternalLinks()
 	}
+	w.client.CloseIdleConnections()
 
-	database.UpdateJobStatus(w.JobID, "completed")
+	w.mu.Lock()
+	canceled, paused, abandoned := w.canceled, w.paused, w.abandoned
+	w.mu.Unlock()
+	w.stop()
+
+	switch {
+	case abandoned:
+		// The job was deleted or handed to another node, which now owns its row and frontier
+		w.report("Job abandoned")
+	case canceled:
+		w.finishJob("canceled")
+		w.report("Job canceled")
+	case paused:
+		// The frontier is kept until the job is resumed
+		w.report("Job paused")
+	default:
+		w.finishJob("completed")
+		if err := database.ClearFrontier(w.JobID); err != nil {
+			log.Printf("⚠️ Failed to clear frontier for job %d: %v", w.JobID, err)
+		}
+		w.report("Job completed")
+	}
 }
 
-// Crawl a single URL and store it in the database
-func (w *Worker) crawl(urlStr string) {
-	defer w.wg.Done()
+// finishJob records the job's terminal status and a summary of what it got done
+func (w *Worker) finishJob(status string) {
+	// A canceled crawl didn't get to look for every page
+	if status == "completed" {
+		w.storeVanished()
+	}
+	if err := database.MarkCrawledLinks(w.JobID); err != nil {
+		log.Printf("⚠️ Failed to mark crawled links for job %d: %v", w.JobID, err)
+	}
+	if err := w.storeLinkMetrics(); err != nil {
+		log.Printf("⚠️ Failed to compute link metrics for job %d: %v", w.JobID, err)
+	}
 
-	// Normalize URL and filter out external domains
-	absoluteURL := w.resolveURL(urlStr)
-	if absoluteURL == "" {
+	summary, err := database.FinishJob(w.JobID, status)
+	if err != nil {
+		log.Printf("❌ Failed to finish job %d: %v", w.JobID, err)
 		return
 	}
+	log.Printf("🏁 Job %d %s: %d pages, %d failed, %d skipped, %d remaining",
+		w.JobID, status, summary.Pages, summary.Failed, summary.Skipped, summary.Remaining)
+}
+
+// report sends a status message to the callback, if any
+func (w *Worker) report(message string) {
+	if w.StatusCb != nil {
+		w.StatusCb(w.JobID, message)
+	}
+}
+
+// enqueue resolves each item's URL against base and adds the in-scope, unseen ones to the frontier.
+// New URLs are persisted before they are queued so an interrupted job resumes with them.
+func (w *Worker) enqueue(base *url.URL, items ...frontierItem) int {
+	resolved := make([]frontierItem, 0, len(items))
+	for _, item := range items {
+		if item.URL, item.Fetch = w.resolveLink(base, item.fetchURL()); item.URL != "" {
+			resolved = append(resolved, item)
+		}
+	}
+
+	var added []frontierItem
+	w.mu.Lock()
+	for _, item := range resolved {
+		if !w.visited[item.URL] {
+			w.visited[item.URL] = true
+			added = append(added, item)
+		}
+	}
+	w.mu.Unlock()
+	if len(added) == 0 {
+		return 0
+	}
+
+	w.persistFrontier(added, database.FrontierQueued)
+
+	w.mu.Lock()
+	for _, item := range added {
+		w.frontier.push(item)
+	}
+	w.cond.Broadcast()
+	w.mu.Unlock()
+	return len(added)
+}
 
+// claim marks a URL discovered while crawling (redirect target, canonical) as visited.
+// It returns false if the URL was already queued or crawled, i.e. the page is a duplicate.
+func (w *Worker) claim(absoluteURL string) bool {
 	w.mu.Lock()
-	if w.counter >= w.Config.MaxLinks || w.visited[absoluteURL] {
+	if w.visited[absoluteURL] {
 		w.mu.Unlock()
-		return
+		return false
 	}
 	w.visited[absoluteURL] = true
-	w.counter++
 	w.mu.Unlock()
 
-	// Send progress message
-	if w.StatusCb != nil {
-		w.StatusCb(w.JobID, "Crawling: "+absoluteURL)
+	w.persistFrontier([]frontierItem{{URL: absoluteURL}}, database.FrontierClaimed)
+	return true
+}
+
+// persistFrontier stores discovered URLs in the job's persisted frontier
+func (w *Worker) persistFrontier(items []frontierItem, state string) {
+	entries := make([]database.FrontierEntry, len(items))
+	for i, item := range items {
+		entries[i] = database.FrontierEntry{
+			JobID:    w.JobID,
+			URL:      item.URL,
+			FetchURL: item.Fetch,
+			Depth:    item.Depth,
+			Priority: item.Priority,
+			Source:   item.Source,
+			State:    state,
+		}
+		if !item.LastMod.IsZero() {
+			lastMod := item.LastMod
+			entries[i].LastMod = &lastMod
+		}
+	}
+	if err := database.AddFrontierEntries(w.ctx, entries); err != nil && w.ctx.Err() == nil {
+		log.Printf("⚠️ Failed to persist frontier for job %d: %v", w.JobID, err)
+	}
+}
+
+// settle records that a dequeued URL is finished, so it is not crawled again on resume
+func (w *Worker) settle(urlStr, state string) {
+	if err := database.SettleFrontierEntry(w.ctx, w.JobID, urlStr, state); err != nil && w.ctx.Err() == nil {
+		log.Printf("⚠️ Failed to update frontier for job %d: %v", w.JobID, err)
 	}
+}
 
-	req, err := http.NewRequest("GET", absoluteURL, nil)
+// Resume restores the frontier and visited set of an interrupted job so Start continues where it stopped
+func (w *Worker) Resume() error {
+	entries, err := database.GetFrontier(w.ctx, w.JobID)
 	if err != nil {
-		log.Printf("Error creating HTTP request: %v", err)
-		return
+		return err
+	}
+	// URLs stored just before the process stopped may not have been settled yet
+	storedURLs, err := database.GetStoredURLs(w.ctx, w.JobID)
+	if err != nil {
+		return err
+	}
+	stored := make(map[string]bool, len(storedURLs))
+	for _, storedURL := range storedURLs {
+		stored[storedURL] = true
+	}
+
+	var settled []string
+	w.mu.Lock()
+	for _, entry := range entries {
+		w.visited[entry.URL] = true
+		if entry.State == database.FrontierQueued && stored[entry.URL] {
+			settled = append(settled, entry.URL)
+			entry.State = database.FrontierDone
+		}
+		switch entry.State {
+		case database.FrontierQueued:
+			// Includes URLs that were in flight when the process stopped
+			item := frontierItem{URL: entry.URL, Fetch: entry.FetchURL, Depth: entry.Depth, Priority: entry.Priority, Source: entry.Source}
+			if entry.LastMod != nil {
+				item.LastMod = *entry.LastMod
+			}
+			w.frontier.push(item)
+		case database.FrontierDone:
+			w.counter++
+			w.done++
+		}
 	}
+	// A job that stopped before persisting anything is seeded from scratch
+	w.resumed = len(entries) > 0
+	w.mu.Unlock()
 
-	resp, err := http.DefaultClient.Do(req)
+	for _, urlStr := range settled {
+		w.settle(urlStr, database.FrontierDone)
+	}
+	return nil
+}
+
+// fetcher pulls URLs from the frontier until the crawl is finished
+func (w *Worker) fetcher() {
+	defer w.wg.Done()
+	for {
+		item, ok := w.next()
+		if !ok {
+			return
+		}
+		w.crawl(item)
+		w.finish(item)
+	}
+}
+
+// next blocks until a URL is available, or returns false once the crawl is over
+func (w *Worker) next() (frontierItem, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for {
+		if w.canceled || w.paused || w.counter >= w.Config.MaxLinks {
+			return frontierItem{}, false
+		}
+		if item, ok := w.frontier.pop(); ok {
+			w.counter++
+			w.inFlight++
+			return item, true
+		}
+		// Nothing queued and nothing left that could queue more
+		if w.inFlight == 0 {
+			w.cond.Broadcast()
+			return frontierItem{}, false
+		}
+		w.cond.Wait()
+	}
+}
+
+// finish marks an in-flight URL as done and wakes idle fetchers
+func (w *Worker) finish(item frontierItem) {
+	w.settle(item.URL, database.FrontierDone)
+
+	w.mu.Lock()
+	w.inFlight--
+	w.done++
+	w.cond.Broadcast()
+	w.mu.Unlock()
+}
+
+// skip records a URL that was deliberately not fetched and gives its slot back to MaxLinks
+func (w *Worker) skip(urlStr string, fetchErr *FetchError) {
+	w.recordAttempt(urlStr, 0, fetchErr, true)
+	w.unclaim(urlStr, fetchErr.Error())
+}
+
+// unclaim gives a skipped URL's slot back to MaxLinks
+func (w *Worker) unclaim(urlStr, reason string) {
+	log.Printf("⏭️ Skipping %s: %s", urlStr, reason)
+	w.settle(urlStr, database.FrontierSkipped)
+
+	w.mu.Lock()
+	w.counter--
+	w.mu.Unlock()
+
+	w.report("Skipped: " + urlStr + " (" + reason + ")")
+}
+
+// Crawl a single URL and store it in the database
+func (w *Worker) crawl(item frontierItem) {
+	absoluteURL := item.URL
+
+	// The URL is requested as written; the normalized form identifies it in the job's records
+	parsedURL, err := url.Parse(item.fetchURL())
 	if err != nil {
-		log.Printf("Error fetching URL: %v", err)
 		return
 	}
-	defer resp.Body.Close()
 
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	metadata := map[string]interface{}{}
+	if item.Fetch != "" {
+		metadata["request_url"] = item.Fetch
+	}
+	if !w.checkRobots(absoluteURL, parsedURL, metadata) {
+		return
+	}
+
+	// Send progress message
+	w.report("Crawling: " + absoluteURL)
+
+	doc, resp, err := w.fetchPage(absoluteURL, parsedURL)
 	if err != nil {
-		log.Printf("Error parsing HTML: %v", err)
+		// Resources skipped for size or type have already been recorded by fetchPage
+		var fetchErr *FetchError
+		if errors.As(err, &fetchErr) && fetchErr.Skipped() {
+			w.unclaim(absoluteURL, fetchErr.Error())
+			return
+		}
+		if errors.As(err, &fetchErr) {
+			w.storeGone(item, fetchErr)
+		}
+		if w.ctx.Err() == nil {
+			log.Printf("Error fetching URL %s: %v", absoluteURL, err)
+		}
+		return
+	}
+	if location, unfollowed := unfollowedRedirect(resp); unfollowed {
+		// Redirects aren't followed with max_redirects 0; the target is queued like a link instead
+		w.enqueue(resp.Request.URL, frontierItem{URL: location, Depth: item.Depth, Priority: item.Priority, Source: item.Source})
+		w.unclaim(absoluteURL, "redirects to "+location)
+		return
+	}
+	if resp.StatusCode == http.StatusNotModified {
+		// Its links are queued from the previous crawl
+		w.storeUnchanged(item, resp)
+		return
+	}
+
+	// Links are relative to the final URL after redirects, or to <base href>
+	pageBase := resp.Request.URL
+	if href, exists := doc.Find("base[href]").First().Attr("href"); exists {
+		if parsedBase, err := pageBase.Parse(href); err == nil {
+			pageBase = parsedBase
+		}
+	}
+
+	// Collapse redirect targets and canonical URLs so each page is stored once
+	pageURL := absoluteURL
+	finalURL := w.resolveURL(resp.Request.URL, resp.Request.URL.String())
+	if finalURL == "" {
+		// The content belongs to another site, not to the in-scope URL that redirected there
+		w.unclaim(absoluteURL, "redirected out of scope to "+resp.Request.URL.String())
+		return
+	}
+	if finalURL != absoluteURL && !w.claim(finalURL) {
+		w.unclaim(absoluteURL, "duplicate of "+finalURL)
 		return
 	}
+	if canonical := w.canonicalURL(doc, pageBase); canonical != "" && canonical != absoluteURL && canonical != finalURL {
+		if !w.claim(canonical) {
+			w.unclaim(absoluteURL, "duplicate of canonical "+canonical)
+			return
+		}
+		metadata["fetched_url"] = absoluteURL
+		delete(metadata, "request_url") // Not how the canonical URL is written
+		pageURL = canonical
+	}
 
 	title := doc.Find("title").Text()
-	content := doc.Find("body").Text()
+	content := w.extractContent(doc, pageBase)
 
-	metadata := map[string]interface{}{
-		"status":    resp.StatusCode,
-		"timestamp": time.Now().Format(time.RFC3339),
+	metadata["status"] = resp.StatusCode
+	metadata["timestamp"] = time.Now().Format(time.RFC3339)
+	if hops := redirectChain(resp); len(hops) > 0 {
+		metadata["redirects"] = hops
+		metadata["final_url"] = resp.Request.URL.String()
+	}
+	extractMetadata(doc, resp, pageBase, metadata)
+	if timings := responseTimings(resp); timings != nil {
+		metadata["timings"] = timings.metadata()
 	}
 
 	// Store page in database
-	database.AddPage(w.JobID, absoluteURL, title, content, metadata)
-
-	// Store result in WorkerResult
-	w.mu.Lock()
-	w.Results = append(w.Results, WorkerResult{
-		URL:     absoluteURL,
-		Title:   title,
-		Content: content,
-	})
-	w.mu.Unlock()
+	hash := contentHash(title, content.Text)
+	page := &database.Page{
+		JobID:        w.JobID,
+		URL:          pageURL,
+		Title:        title,
+		Content:      content.Text,
+		ContentHTML:  content.HTML,
+		Markdown:     content.Markdown,
+		Depth:        item.Depth,
+		Source:       item.Source,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		ContentHash:  hash,
+		ChangeStatus: w.changeStatus(pageURL, hash),
+		Extracted:    w.extract(doc, pageURL),
+	}
+	if !w.storePage(page, metadata) {
+		return // Canceled: nothing more is written for this job
+	}
 
-	// Extract and queue internal links
+	// Record every link on the page, and queue the internal ones for the next level
+	var links []frontierItem
+	var edges []database.Link
 	doc.Find("a").Each(func(_ int, s *goquery.Selection) {
 		href, exists := s.Attr("href")
 		if exists {
-			resolvedURL := w.resolveURL(href)
-			if resolvedURL != "" {
-				w.wg.Add(1)
-				go w.crawl(resolvedURL)
+			links = append(links, frontierItem{URL: href, Depth: item.Depth + 1, Priority: defaultPriority, Source: SourceLink})
+			if edge, ok := w.pageLink(page, pageBase, s, href); ok {
+				edges = append(edges, edge)
 			}
 		}
 	})
+	if page.ID != 0 {
+		w.storeLinks(edges)
+	}
+
+	// Stop expanding once the requested depth is reached
+	if w.Config.SitemapOnly || (w.Config.MaxDepth > 0 && item.Depth >= w.Config.MaxDepth) {
+		return
+	}
+	w.enqueue(pageBase, links...)
 }
 
-// **resolveURL ensures that URLs are absolute and belong to the same domain**
-func (w *Worker) resolveURL(href string) string {
-	parsedBase, err := url.Parse(w.StartURL)
+// storePage saves a crawled page and adds it to the results, returning false if the job was canceled
+func (w *Worker) storePage(page *database.Page, metadata map[string]interface{}) bool {
+	err := database.AddPage(w.ctx, page, metadata)
+	if w.ctx.Err() != nil {
+		return false
+	}
 	if err != nil {
-		return ""
+		log.Printf("❌ Failed to store page %s: %v", page.URL, err)
 	}
 
-	parsedURL, err := url.Parse(href)
+	// Store result in WorkerResult
+	w.mu.Lock()
+	w.Results = append(w.Results, WorkerResult{
+		URL:     page.URL,
+		Title:   page.Title,
+		Content: page.Content,
+		Depth:   page.Depth,
+	})
+	w.mu.Unlock()
+	return true
+}
+
+// resolveURL makes href absolute against base and normalizes it, returning "" if it is not an in-scope web page
+func (w *Worker) resolveURL(base *url.URL, href string) string {
+	normalized, _ := w.resolveLink(base, href)
+	return normalized
+}
+
+// resolveLink is resolveURL that also returns the absolute URL as written, without its fragment,
+// or "" if it normalizes to itself
+func (w *Worker) resolveLink(base *url.URL, href string) (normalized, written string) {
+	parsedURL, err := url.Parse(strings.TrimSpace(href))
 	if err != nil {
-		return ""
+		return "", ""
 	}
 
 	// Convert relative URLs to absolute URLs
-	resolvedURL := parsedBase.ResolveReference(parsedURL)
+	resolvedURL := base.ResolveReference(parsedURL)
 
-	// Ignore external domains
-	if resolvedURL.Host != w.Host {
-		return ""
+	// Ignore mailto, tel, javascript and other non-web links
+	scheme := strings.ToLower(resolvedURL.Scheme)
+	if scheme != "http" && scheme != "https" {
+		return "", ""
+	}
+
+	normalizedURL := normalizeURL(resolvedURL, w.Config.Normalize)
+
+	// Ignore external domains and excluded paths
+	if !w.scope.allows(normalizedURL) {
+		return "", ""
+	}
+
+	normalized = normalizedURL.String()
+	resolvedURL.Fragment, resolvedURL.RawFragment = "", ""
+	if written = resolvedURL.String(); written == normalized {
+		written = ""
 	}
+	return normalized, written
+}
 
-	// Ignore mailto, tel, javascript, and fragment (#) links
-	if strings.HasPrefix(resolvedURL.String(), "mailto:") ||
-		strings.HasPrefix(resolvedURL.String(), "tel:") ||
-		strings.HasPrefix(resolvedURL.String(), "javascript:") ||
-		strings.Contains(resolvedURL.String(), "#") {
+// canonicalURL returns the in-scope <link rel="canonical"> of a page, if it declares one
+func (w *Worker) canonicalURL(doc *goquery.Document, base *url.URL) string {
+	if w.Config.Normalize.IgnoreCanonical {
 		return ""
 	}
 
-	return resolvedURL.String()
+	canonical := ""
+	doc.Find("link[rel][href]").EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		rel, _ := s.Attr("rel")
+		for _, value := range strings.Fields(rel) {
+			if strings.EqualFold(value, "canonical") {
+				href, _ := s.Attr("href")
+				canonical = w.resolveURL(base, href)
+				return false
+			}
+		}
+		return true
+	})
+	return canonical
 }
 
 // GetStatus returns the current processed count and the maximum number of links.
@@ -203,3 +710,21 @@ func (w *Worker) GetStatus() (processed int, total int) {
 	defer w.mu.Unlock()
 	return w.counter, w.Config.MaxLinks
 }
+
+// Scope returns the effective crawl scope and how many URLs it rejected, by reason.
+func (w *Worker) Scope() EffectiveScope {
+	return w.scope.effective()
+}
+
+// GetProgress returns queued, in-flight and done counts alongside the processed total.
+func (w *Worker) GetProgress() WorkerProgress {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return WorkerProgress{
+		Queued:    w.frontier.Len(),
+		InFlight:  w.inFlight,
+		Done:      w.done,
+		Processed: w.counter,
+		Total:     w.Config.MaxLinks,
+	}
+}